@@ -6,6 +6,7 @@ package appconfig
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 )
@@ -26,6 +27,7 @@ type AppConfig struct {
 	VerifyToken string `json:"verify_token"`
 	EncryptKey  string `json:"encrypt_key"`
 	AppType     string `json:"app_type"`
+	BotOpenID   string `json:"bot_open_id"` // the open id of the bot, to recognize the bot mention in the post message
 }
 
 type AppTokenManager struct {
@@ -109,6 +111,18 @@ func (a *AppTokenManager) SetTenantAccessToken(tenantKey string, tenantAccessTok
 	return nil
 }
 
+// TenantKeys the tenants whose access token has been cached
+func (a *AppTokenManager) TenantKeys() []string {
+	a.rwMuTenant.RLock()
+	defer a.rwMuTenant.RUnlock()
+
+	tenantKeys := make([]string, 0, len(a.TenantAccessToken))
+	for tenantKey := range a.TenantAccessToken {
+		tenantKeys = append(tenantKeys, tenantKey)
+	}
+	return tenantKeys
+}
+
 func (a *AppTokenManager) DisableTenantAccessToken(tenantKey string) error {
 	a.rwMuTenant.Lock()
 	defer a.rwMuTenant.Unlock()
@@ -140,6 +154,19 @@ func GetConfig(appID string) (AppConfig, error) {
 	return appConf, nil
 }
 
+// GetAllConfig the configs of all apps, sorted by app id
+func GetAllConfig() []AppConfig {
+	appConfs := make([]AppConfig, 0, len(appConfMap))
+	for _, v := range appConfMap {
+		appConfs = append(appConfs, v)
+	}
+	sort.Slice(appConfs, func(i, j int) bool {
+		return appConfs[i].AppID < appConfs[j].AppID
+	})
+
+	return appConfs
+}
+
 func GetTokenManager(appID string) (*AppTokenManager, error) {
 	tokenManager, ok := appTokenMap[appID]
 	if !ok {
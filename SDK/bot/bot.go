@@ -0,0 +1,344 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+// Package bot own the lifecycle of the SDK: the app configs, the logger, the router,
+// the http client, the stores and the background workers. demo:
+//
+// b, err := bot.New(&bot.Option{
+// 	Apps:    []appconfig.AppConfig{conf},
+// 	Async:   event.DefaultAsyncOption(),
+// 	Workers: []bot.Worker{bot.HTTPServer(&http.Server{Addr: ":8089", Handler: r})},
+// })
+// ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+// defer stop()
+// err = b.Run(ctx) // block until SIGTERM, then shutdown gracefully
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/larksuite/botframework-go/SDK/appconfig"
+	"github.com/larksuite/botframework-go/SDK/auth"
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/message"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+const (
+	DefaultShutdownTimeout = 30 * time.Second
+)
+
+var (
+	ErrStarted    = errors.New("bot has been started")
+	ErrNotStarted = errors.New("bot has not been started")
+)
+
+// Worker the background service of the bot, eg: the http server, the websocket client(wsclient.Client)
+type Worker interface {
+	// Start block until the worker is stopped by Shutdown.
+	// ctx carries the values only, it's not canceled when the bot is shutting down.
+	Start(ctx context.Context) error
+	// Shutdown stop the worker and wait for the work in flight
+	Shutdown(ctx context.Context) error
+}
+
+type Option struct {
+	Apps          []appconfig.AppConfig
+	Logger        common.LogInterface // nil means the logger initialized by common.InitLogger, or the default one
+	LoggerOption  interface{}
+	Router        *event.Router      // nil means event.DefaultRouter()
+	HTTPClient    *http.Client       // the client used to call the open platform apis, nil means the default one
+	TicketManager auth.TicketManager // the app ticket store of the ISV apps
+	Async         *event.AsyncOption // enable the async dispatch, nil means the events are handled in the callback
+
+	// refresh the app/tenant access tokens before they expire in background,
+	// 0 means the tokens are fetched when the open platform apis are called
+	TokenRefreshInterval time.Duration
+	ShutdownTimeout      time.Duration // the timeout of Shutdown called by Run, 0 means DefaultShutdownTimeout
+
+	Workers []Worker
+}
+
+type Bot struct {
+	option *Option
+
+	started      bool
+	workerErr    chan error
+	workerWg     sync.WaitGroup
+	stopRefresh  context.CancelFunc
+	refreshWg    sync.WaitGroup
+	shutdownOnce sync.Once
+	shutdownErr  error
+	mu           sync.Mutex
+}
+
+func New(option *Option) (*Bot, error) {
+	if option == nil {
+		option = &Option{}
+	}
+	if option.TokenRefreshInterval < 0 {
+		return nil, fmt.Errorf("tokenRefreshInterval[%v] invalid", option.TokenRefreshInterval)
+	}
+
+	opt := *option
+	if opt.Router == nil {
+		opt.Router = event.DefaultRouter()
+	}
+	if opt.ShutdownTimeout <= 0 {
+		opt.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	opt.Workers = append([]Worker{}, option.Workers...)
+
+	return &Bot{
+		option:    &opt,
+		workerErr: make(chan error, len(opt.Workers)),
+	}, nil
+}
+
+// Router register the handlers of the bot
+func (b *Bot) Router() *event.Router {
+	return b.option.Router
+}
+
+// Start initialize the SDK and start the workers in background.
+// The workers are started with the values of ctx, but not with its cancellation, they are stopped by Shutdown only.
+func (b *Bot) Start(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.started {
+		return ErrStarted
+	}
+
+	if b.option.Logger != nil {
+		common.InitLogger(b.option.Logger, b.option.LoggerOption)
+	}
+	if b.option.HTTPClient != nil {
+		common.SetHTTPClient(b.option.HTTPClient)
+	}
+	if len(b.option.Apps) != 0 {
+		appconfig.Init(b.option.Apps...)
+	}
+	if b.option.TicketManager != nil {
+		err := auth.InitISVAppTicketManager(b.option.TicketManager)
+		if err != nil {
+			return err
+		}
+	}
+	if b.option.Async != nil {
+		err := event.EnableAsyncDispatch(b.option.Async)
+		if err != nil {
+			return err
+		}
+	}
+
+	if b.option.TokenRefreshInterval > 0 {
+		refreshCtx, cancel := context.WithCancel(context.Background())
+		b.stopRefresh = cancel
+		b.refreshWg.Add(1)
+		go b.refreshTokens(refreshCtx)
+	}
+
+	// the work in flight of the workers is waited by Shutdown, instead of being canceled with ctx
+	workerCtx := common.DetachContext(ctx)
+	for _, worker := range b.option.Workers {
+		b.workerWg.Add(1)
+		go func(worker Worker) {
+			defer b.workerWg.Done()
+			defer common.RecoverPanic(workerCtx)
+
+			err := worker.Start(workerCtx)
+			if err != nil {
+				common.Logger(ctx).Errorf("bot: workerError[%v]", err)
+				b.workerErr <- err
+			}
+		}(worker)
+	}
+
+	b.started = true
+	common.Logger(ctx).Infof("bot: started, apps[%d]workers[%d]", len(b.option.Apps), len(b.option.Workers))
+	return nil
+}
+
+// Run start the bot and block until ctx is done or a worker fails, then shutdown the bot with ShutdownTimeout
+func (b *Bot) Run(ctx context.Context) error {
+	err := b.Start(ctx)
+	if err != nil {
+		return err
+	}
+
+	var workerErr error
+	select {
+	case <-ctx.Done():
+	case workerErr = <-b.workerErr:
+	}
+
+	// ctx is done, shutdown with a new one
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), b.option.ShutdownTimeout)
+	defer cancel()
+
+	err = b.Shutdown(shutdownCtx)
+	if workerErr != nil {
+		return workerErr
+	}
+	return err
+}
+
+// Shutdown stop the bot gracefully:
+// 1. shutdown the workers, eg: the http server stops accepting requests and waits for the requests in flight
+// 2. reject the new callbacks of the router, and wait for the callbacks being handled
+// 3. wait for the events queued by the async dispatch
+// 4. stop the token refresher, clear the caches and flush the logs
+//
+// Return the first error, the remaining steps are still done. Return error if ctx is done before all steps are finished.
+func (b *Bot) Shutdown(ctx context.Context) error {
+	b.mu.Lock()
+	started := b.started
+	b.mu.Unlock()
+	if !started {
+		return ErrNotStarted
+	}
+
+	b.shutdownOnce.Do(func() {
+		b.shutdownErr = b.doShutdown(ctx)
+	})
+	return b.shutdownErr
+}
+
+func (b *Bot) doShutdown(ctx context.Context) error {
+	var errs []error
+	record := func(step string, err error) {
+		if err != nil {
+			common.Logger(ctx).Errorf("bot: shutdown %s error[%v]", step, err)
+			errs = append(errs, fmt.Errorf("%s: %v", step, err))
+		}
+	}
+
+	// the workers are shutdown concurrently
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for _, worker := range b.option.Workers {
+		wg.Add(1)
+		go func(worker Worker) {
+			defer wg.Done()
+
+			err := worker.Shutdown(ctx)
+			mu.Lock()
+			record("worker", err)
+			mu.Unlock()
+		}(worker)
+	}
+	wg.Wait()
+	record("worker", wait(ctx, &b.workerWg))
+
+	record("router", b.option.Router.Shutdown(ctx))
+
+	if b.option.Async != nil {
+		record("async dispatch", event.ShutdownAsyncDispatch(ctx))
+	}
+
+	if b.stopRefresh != nil {
+		b.stopRefresh()
+		record("token refresher", wait(ctx, &b.refreshWg))
+	}
+
+	message.LruCache.Purge()
+
+	common.Logger(ctx).Infof("bot: shutdown, errors[%d]", len(errs))
+	common.FlushLogger()
+
+	if len(errs) != 0 {
+		return fmt.Errorf("bot shutdown error%v", errs)
+	}
+	return nil
+}
+
+func (b *Bot) refreshTokens(ctx context.Context) {
+	defer b.refreshWg.Done()
+
+	ticker := time.NewTicker(b.option.TokenRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			RefreshTokens(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// RefreshTokens get the app access token of each app, and the tenant access tokens cached before.
+// The tokens are fetched from the open platform only if they are about to expire(see appconfig.ExpireInterval).
+func RefreshTokens(ctx context.Context) {
+	for _, appConf := range appconfig.GetAllConfig() {
+		if ctx.Err() != nil {
+			return
+		}
+
+		_, err := auth.GetAppAccessToken(ctx, appConf.AppID)
+		if err != nil {
+			common.Logger(ctx).Warnf("bot: refresh app access token error[%v]appid[%s]", err, appConf.AppID)
+		}
+
+		tokenManager, err := appconfig.GetTokenManager(appConf.AppID)
+		if err != nil {
+			continue
+		}
+		tenantKeys := tokenManager.TenantKeys()
+		if appConf.AppType != protocol.ISVApp && len(tenantKeys) == 0 {
+			// the tenant key is not used by the internal app
+			tenantKeys = []string{""}
+		}
+		for _, tenantKey := range tenantKeys {
+			_, err = auth.GetTenantAccessToken(ctx, tenantKey, appConf.AppID)
+			if err != nil {
+				common.Logger(ctx).Warnf("bot: refresh tenant access token error[%v]appid[%s]tenant[%s]", err, appConf.AppID, tenantKey)
+			}
+		}
+	}
+}
+
+func wait(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// HTTPServer the worker of http.Server, the requests in flight are waited when shutting down
+func HTTPServer(server *http.Server) Worker {
+	return &httpServerWorker{server: server}
+}
+
+type httpServerWorker struct {
+	server *http.Server
+}
+
+func (w *httpServerWorker) Start(ctx context.Context) error {
+	err := w.server.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+func (w *httpServerWorker) Shutdown(ctx context.Context) error {
+	return w.server.Shutdown(ctx)
+}
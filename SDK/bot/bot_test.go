@@ -0,0 +1,278 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package bot_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/larksuite/botframework-go/SDK/appconfig"
+	"github.com/larksuite/botframework-go/SDK/bot"
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+	"github.com/larksuite/botframework-go/SDK/wsclient"
+)
+
+const testVerifyToken = "test_verify_token"
+
+func newTestEventBody(appID, uuid string) string {
+	return fmt.Sprintf(`{"uuid":"%s","ts":"1577808000.000","token":"%s","type":"event_callback","event":{"type":"%s","app_id":"%s","tenant_key":"tenant","open_chat_id":"oc_1"}}`,
+		uuid, testVerifyToken, protocol.EventTypeMessage, appID)
+}
+
+type testWorker struct {
+	started  int32
+	shutdown int32
+	stop     chan struct{}
+	err      error
+	startCtx atomic.Value // the ctx of Start
+	ctxErr   atomic.Value // the error of the ctx of Start when Shutdown is called
+}
+
+func newTestWorker(err error) *testWorker {
+	return &testWorker{stop: make(chan struct{}), err: err}
+}
+
+func (w *testWorker) Start(ctx context.Context) error {
+	w.startCtx.Store(ctx)
+	atomic.StoreInt32(&w.started, 1)
+	if w.err != nil {
+		return w.err
+	}
+	<-w.stop
+	return nil
+}
+
+func (w *testWorker) Shutdown(ctx context.Context) error {
+	if startCtx, ok := w.startCtx.Load().(context.Context); ok && startCtx.Err() != nil {
+		w.ctxErr.Store(startCtx.Err())
+	}
+	if atomic.CompareAndSwapInt32(&w.shutdown, 0, 1) {
+		close(w.stop)
+	}
+	return nil
+}
+
+func TestBotShutdownDrain(t *testing.T) {
+	appID := "cli_test_bot_drain"
+	router := event.NewRouter()
+	worker := newTestWorker(nil)
+
+	b, err := bot.New(&bot.Option{
+		Apps: []appconfig.AppConfig{{
+			AppID:       appID,
+			AppType:     protocol.InternalApp,
+			VerifyToken: testVerifyToken,
+		}},
+		Router:  router,
+		Workers: []bot.Worker{worker},
+	})
+	if err != nil {
+		t.Fatalf("New: err[%v]", err)
+	}
+
+	entered := make(chan struct{})
+	var finished int32
+	router.EventRegister(appID, protocol.EventTypeMessage, func(ctx context.Context, eventBody []byte) error {
+		close(entered)
+		time.Sleep(50 * time.Millisecond)
+		atomic.StoreInt32(&finished, 1)
+		return nil
+	})
+
+	ctx := context.Background()
+	if err = b.Start(ctx); err != nil {
+		t.Fatalf("Start: err[%v]", err)
+	}
+	if err = b.Start(ctx); err != bot.ErrStarted {
+		t.Errorf("Start again: err[%v]", err)
+	}
+
+	go router.EventCallback(ctx, newTestEventBody(appID, "bot_drain_1"), appID)
+	<-entered
+
+	// the handler in flight is waited
+	if err = b.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown: err[%v]", err)
+	}
+	if atomic.LoadInt32(&finished) != 1 {
+		t.Errorf("Shutdown returns before the handler finished")
+	}
+	if atomic.LoadInt32(&worker.started) != 1 || atomic.LoadInt32(&worker.shutdown) != 1 {
+		t.Errorf("worker: started[%d]shutdown[%d]", worker.started, worker.shutdown)
+	}
+
+	// the new callbacks are rejected
+	_, err = router.EventCallback(ctx, newTestEventBody(appID, "bot_drain_2"), appID)
+	if err == nil {
+		t.Errorf("callback after shutdown: err is nil")
+	}
+}
+
+func TestBotShutdownTimeout(t *testing.T) {
+	appID := "cli_test_bot_timeout"
+	router := event.NewRouter()
+	appconfig.Init(appconfig.AppConfig{AppID: appID, AppType: protocol.InternalApp, VerifyToken: testVerifyToken})
+
+	release := make(chan struct{})
+	defer close(release)
+	entered := make(chan struct{})
+	router.EventRegister(appID, protocol.EventTypeMessage, func(ctx context.Context, eventBody []byte) error {
+		close(entered)
+		<-release
+		return nil
+	})
+
+	b, _ := bot.New(&bot.Option{Router: router})
+	ctx := context.Background()
+	b.Start(ctx)
+
+	go router.EventCallback(ctx, newTestEventBody(appID, "bot_timeout_1"), appID)
+	<-entered
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := b.Shutdown(shutdownCtx); err == nil {
+		t.Errorf("Shutdown: err is nil")
+	}
+}
+
+func TestBotRun(t *testing.T) {
+	// Run returns when ctx is done
+	worker := newTestWorker(nil)
+	b, _ := bot.New(&bot.Option{Router: event.NewRouter(), Workers: []bot.Worker{worker}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := b.Run(ctx); err != nil {
+		t.Errorf("Run: err[%v]", err)
+	}
+	if atomic.LoadInt32(&worker.shutdown) != 1 {
+		t.Errorf("worker is not shutdown")
+	}
+	// the worker is stopped by Shutdown, not by ctx
+	if err := worker.ctxErr.Load(); err != nil {
+		t.Errorf("worker ctx is canceled before Shutdown: err[%v]", err)
+	}
+
+	// Run returns the error of the worker
+	workerErr := errors.New("listen error")
+	b, _ = bot.New(&bot.Option{Router: event.NewRouter(), Workers: []bot.Worker{newTestWorker(workerErr)}})
+	if err := b.Run(context.Background()); err != workerErr {
+		t.Errorf("Run: err[%v]", err)
+	}
+}
+
+func TestDrainer(t *testing.T) {
+	drainer := &common.Drainer{}
+	if !drainer.Acquire() {
+		t.Fatalf("Acquire: false")
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		drainer.Release()
+	}()
+	if err := drainer.Drain(context.Background()); err != nil {
+		t.Errorf("Drain: err[%v]", err)
+	}
+	if drainer.Acquire() {
+		t.Errorf("Acquire after drain: true")
+	}
+}
+
+func TestBotSignalWsClient(t *testing.T) {
+	appID := "cli_test_bot_signal"
+	conns := make(chan *websocket.Conn, 1)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err == nil {
+			conns <- conn
+		}
+	}))
+	defer server.Close()
+
+	router := event.NewRouter()
+	handling := make(chan struct{})
+	release := make(chan struct{})
+	var handlerErr atomic.Value
+	router.EventRegister(appID, protocol.EventTypeMessage, func(ctx context.Context, eventBody []byte) error {
+		close(handling)
+		<-release
+		if ctx.Err() != nil {
+			handlerErr.Store(ctx.Err())
+		}
+		return nil
+	})
+
+	client, _ := wsclient.NewClient(&wsclient.Option{URL: "ws" + strings.TrimPrefix(server.URL, "http"), AppID: appID, Router: router})
+	b, _ := bot.New(&bot.Option{
+		Apps: []appconfig.AppConfig{{
+			AppID:       appID,
+			AppType:     protocol.InternalApp,
+			VerifyToken: testVerifyToken,
+		}},
+		Router:  router,
+		Workers: []bot.Worker{client},
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+	defer stop()
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- b.Run(ctx)
+	}()
+
+	var conn *websocket.Conn
+	select {
+	case conn = <-conns:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("no connection")
+	}
+	err := conn.WriteJSON(&wsclient.Frame{Type: wsclient.FrameTypeEvent, ID: "1", Body: newTestEventBody(appID, "bot_signal_1")})
+	if err != nil {
+		t.Fatalf("write frame error[%v]", err)
+	}
+	<-handling
+
+	// SIGTERM while the frame is in flight, its ack is still written
+	process, _ := os.FindProcess(os.Getpid())
+	process.Signal(syscall.SIGTERM)
+	<-ctx.Done()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	ack := &wsclient.Frame{}
+	err = conn.ReadJSON(ack)
+	if err != nil || ack.ID != "1" || ack.Code != wsclient.AckCodeSuccess {
+		t.Errorf("ack: ack[%+v]err[%v]", ack, err)
+	}
+	if err := handlerErr.Load(); err != nil {
+		t.Errorf("handler ctx: err[%v]", err)
+	}
+
+	select {
+	case err = <-runErr:
+		if err != nil {
+			t.Errorf("Run: err[%v]", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Errorf("Run is not returned after SIGTERM")
+	}
+}
@@ -25,7 +25,7 @@ func GetChatInfo(ctx context.Context, tenantKey, appID string, chatID string) (*
 		return nil, err
 	}
 
-	rspBytes, statusCode, err := common.DoHttpGetOApi(protocol.GetChatInfoPath, common.NewHeaderToken(accessToken),
+	rspBytes, statusCode, err := common.DoHttpGetOApiWithContext(ctx, protocol.GetChatInfoPath, common.NewHeaderToken(accessToken),
 		protocol.GenGetGroupInfoRequest(chatID))
 
 	if err != nil {
@@ -58,7 +58,7 @@ func GetChatList(ctx context.Context, tenantKey, appID string, pageSize int, pag
 		return nil, err
 	}
 
-	rspBytes, statusCode, err := common.DoHttpGetOApi(protocol.GetChatListPath, common.NewHeaderToken(accessToken),
+	rspBytes, statusCode, err := common.DoHttpGetOApiWithContext(ctx, protocol.GetChatListPath, common.NewHeaderToken(accessToken),
 		protocol.GenGetGroupListRequest(pageSize, pageToken))
 
 	if err != nil {
@@ -91,7 +91,7 @@ func CreateChat(ctx context.Context, tenantKey, appID string, request *protocol.
 		return nil, err
 	}
 
-	rspBytes, statusCode, err := common.DoHttpPostOApi(protocol.CreateChatPath, common.NewHeaderToken(accessToken), request)
+	rspBytes, statusCode, err := common.DoHttpPostOApiWithContext(ctx, protocol.CreateChatPath, common.NewHeaderToken(accessToken), request)
 	if err != nil {
 		return nil, common.ErrOpenApiFailed.ErrorWithExtErr(err)
 	}
@@ -122,7 +122,7 @@ func UpdateChatInfo(ctx context.Context, tenantKey, appID string, request *proto
 		return nil, err
 	}
 
-	rspBytes, statusCode, err := common.DoHttpPostOApi(protocol.UpdateChatInfoPath, common.NewHeaderToken(accessToken), request)
+	rspBytes, statusCode, err := common.DoHttpPostOApiWithContext(ctx, protocol.UpdateChatInfoPath, common.NewHeaderToken(accessToken), request)
 	if err != nil {
 		return nil, common.ErrOpenApiFailed.ErrorWithExtErr(err)
 	}
@@ -153,7 +153,7 @@ func AddUserToChat(ctx context.Context, tenantKey, appID string, request *protoc
 		return nil, err
 	}
 
-	rspBytes, statusCode, err := common.DoHttpPostOApi(protocol.AddUserToChatPath, common.NewHeaderToken(accessToken), request)
+	rspBytes, statusCode, err := common.DoHttpPostOApiWithContext(ctx, protocol.AddUserToChatPath, common.NewHeaderToken(accessToken), request)
 	if err != nil {
 		return nil, common.ErrOpenApiFailed.ErrorWithExtErr(err)
 	}
@@ -184,7 +184,7 @@ func DeleteUserFromChat(ctx context.Context, tenantKey, appID string, request *p
 		return nil, err
 	}
 
-	rspBytes, statusCode, err := common.DoHttpPostOApi(protocol.DeleteUserFromChatPath, common.NewHeaderToken(accessToken), request)
+	rspBytes, statusCode, err := common.DoHttpPostOApiWithContext(ctx, protocol.DeleteUserFromChatPath, common.NewHeaderToken(accessToken), request)
 	if err != nil {
 		return nil, common.ErrOpenApiFailed.ErrorWithExtErr(err)
 	}
@@ -215,7 +215,7 @@ func DisbandChat(ctx context.Context, tenantKey, appID string, request *protocol
 		return nil, err
 	}
 
-	rspBytes, statusCode, err := common.DoHttpPostOApi(protocol.DisbandChatPath, common.NewHeaderToken(accessToken), request)
+	rspBytes, statusCode, err := common.DoHttpPostOApiWithContext(ctx, protocol.DisbandChatPath, common.NewHeaderToken(accessToken), request)
 	if err != nil {
 		return nil, common.ErrOpenApiFailed.ErrorWithExtErr(err)
 	}
@@ -4,7 +4,11 @@
 
 package common
 
-import "time"
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
 
 type DBClient interface {
 	InitDB(mapParams map[string]string) error
@@ -12,3 +16,60 @@ type DBClient interface {
 	Set(key string, value interface{}, expiration time.Duration) error
 	Get(key string) (string, error)
 }
+
+// DBClientEx optional interface of DBClient, used for dedup/nonce check.
+// If the DBClient does not implement it, the SDK falls back to Get+Set, which is not atomic.
+type DBClientEx interface {
+	DBClient
+
+	// SetNX set the value only if the key does not exist, return true if the value is set
+	SetNX(key string, value interface{}, expiration time.Duration) (bool, error)
+	Del(key string) error
+}
+
+// SetIfAbsent use SetNX if the client implements DBClientEx, otherwise fall back to Get+Set
+func SetIfAbsent(client DBClient, key string, value interface{}, expiration time.Duration) (bool, error) {
+	if clientEx, ok := client.(DBClientEx); ok {
+		return clientEx.SetNX(key, value, expiration)
+	}
+
+	// not atomic, two requests at the same time may both succeed
+	if v, err := client.Get(key); err == nil && v != "" {
+		return false, nil
+	}
+
+	err := client.Set(key, value, expiration)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// TryLock lock the key by SetIfAbsent with a random token, return the unlock function, or nil if the key is locked by others.
+// The lock expires after ttl in case the holder exits without unlocking.
+// The unlock deletes the key only if it still holds the token, so the lock taken by another holder after the expiration is kept.
+// The check is Get+Del, not atomic: the lock can still expire and be taken between them.
+func TryLock(client DBClient, key string, ttl time.Duration) (func(), error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	token := hex.EncodeToString(buf)
+
+	locked, err := SetIfAbsent(client, key, token, ttl)
+	if err != nil || !locked {
+		return nil, err
+	}
+
+	return func() {
+		if value, err := client.Get(key); err != nil || value != token {
+			return
+		}
+
+		if clientEx, ok := client.(DBClientEx); ok {
+			clientEx.Del(key)
+		} else {
+			client.Set(key, "", time.Millisecond)
+		}
+	}, nil
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package common
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	memoryCleanInterval = time.Minute
+)
+
+// MemoryDBClient in-process DBClient, for single instance service and unit test.
+// The data is lost after the process exits, use DefaultRedisClient when running multiple instances.
+type MemoryDBClient struct {
+	mu        sync.Mutex
+	data      map[string]memoryItem
+	lastClean time.Time
+}
+
+type memoryItem struct {
+	value  string
+	expire time.Time // zero means never expire
+}
+
+func (m memoryItem) expired(now time.Time) bool {
+	return !m.expire.IsZero() && !now.Before(m.expire)
+}
+
+func NewMemoryDBClient() *MemoryDBClient {
+	return &MemoryDBClient{
+		data:      make(map[string]memoryItem),
+		lastClean: time.Now(),
+	}
+}
+
+func (d *MemoryDBClient) InitDB(mapParams map[string]string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.data == nil {
+		d.data = make(map[string]memoryItem)
+	}
+	return nil
+}
+
+func (d *MemoryDBClient) Set(key string, value interface{}, expiration time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.set(key, value, expiration)
+	return nil
+}
+
+func (d *MemoryDBClient) Get(key string) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	item, ok := d.data[key]
+	if !ok {
+		return "", fmt.Errorf("get value error[key not found], key[%s]", key)
+	}
+	if item.expired(time.Now()) {
+		delete(d.data, key)
+		return "", fmt.Errorf("get value error[key expired], key[%s]", key)
+	}
+
+	return item.value, nil
+}
+
+func (d *MemoryDBClient) SetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if item, ok := d.data[key]; ok && !item.expired(time.Now()) {
+		return false, nil
+	}
+
+	d.set(key, value, expiration)
+	return true, nil
+}
+
+func (d *MemoryDBClient) Del(key string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.data, key)
+	return nil
+}
+
+func (d *MemoryDBClient) set(key string, value interface{}, expiration time.Duration) {
+	if d.data == nil {
+		d.data = make(map[string]memoryItem)
+	}
+
+	now := time.Now()
+	item := memoryItem{value: fmt.Sprint(value)}
+	if expiration > 0 {
+		item.expire = now.Add(expiration)
+	}
+	d.data[key] = item
+
+	// remove expired keys
+	if now.Sub(d.lastClean) > memoryCleanInterval {
+		for k, v := range d.data {
+			if v.expired(now) {
+				delete(d.data, k)
+			}
+		}
+		d.lastClean = now
+	}
+}
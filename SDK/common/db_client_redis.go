@@ -55,3 +55,29 @@ func (d *DefaultRedisClient) Get(key string) (string, error) {
 
 	return value, nil
 }
+
+func (d *DefaultRedisClient) SetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
+	if d.Client == nil {
+		return false, fmt.Errorf("db_client isnot initialized, key[%s]", key)
+	}
+
+	ok, err := d.Client.SetNX(key, value, expiration).Result()
+	if err != nil {
+		return false, fmt.Errorf("setnx value error[%v], key[%s]", err, key)
+	}
+
+	return ok, nil
+}
+
+func (d *DefaultRedisClient) Del(key string) error {
+	if d.Client == nil {
+		return fmt.Errorf("db_client isnot initialized, key[%s]", key)
+	}
+
+	_, err := d.Client.Del(key).Result()
+	if err != nil {
+		return fmt.Errorf("del value error[%v], key[%s]", err, key)
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package common
+
+import (
+	"context"
+	"sync"
+)
+
+// Drainer count the work in flight, and wait for it when shutting down. demo:
+// if !drainer.Acquire() {
+// 	return ErrShuttingDown
+// }
+// defer drainer.Release()
+type Drainer struct {
+	inflight int
+	draining bool
+	idle     chan struct{}
+	mu       sync.Mutex
+}
+
+// Acquire return false if Drain has been called, the work should be rejected
+func (d *Drainer) Acquire() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.draining {
+		return false
+	}
+	d.inflight++
+	return true
+}
+
+func (d *Drainer) Release() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.inflight--
+	if d.inflight == 0 && d.idle != nil {
+		close(d.idle)
+		d.idle = nil
+	}
+}
+
+// Stop reject the new work without waiting
+func (d *Drainer) Stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.draining = true
+}
+
+// Drain reject the new work, and wait for the work in flight.
+// Return error if ctx is done before all work is released.
+func (d *Drainer) Drain(ctx context.Context) error {
+	d.mu.Lock()
+	d.draining = true
+	if d.inflight == 0 {
+		d.mu.Unlock()
+		return nil
+	}
+	if d.idle == nil {
+		d.idle = make(chan struct{})
+	}
+	idle := d.idle
+	d.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *Drainer) IsDraining() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.draining
+}
@@ -89,12 +89,28 @@ var (
 	ErrEventTypeUnregistered  = &ErrCodeMsg{Code: 5012, Message: "event notification_type_handler unregistered"}
 	ErrEventHandlerIsNil      = &ErrCodeMsg{Code: 5013, Message: "event handler not found"}
 	ErrEventHandlerFailed     = &ErrCodeMsg{Code: 5014, Message: "event handle function error"}
+	ErrEventDedupParams       = &ErrCodeMsg{Code: 5015, Message: "event dedup params error"}
+	ErrEventAsyncParams       = &ErrCodeMsg{Code: 5016, Message: "event async dispatch params error"}
+	ErrEventQueueFull         = &ErrCodeMsg{Code: 5017, Message: "event async queue is full"}
+	ErrEventQueueClosed       = &ErrCodeMsg{Code: 5018, Message: "event async queue is closed"}
+	ErrEventHandlerPanic      = &ErrCodeMsg{Code: 5019, Message: "event handler panic"}
+	ErrEventSignatureInvalid  = &ErrCodeMsg{Code: 5020, Message: "event signature invalid"}
+	ErrEventTimestampExpired  = &ErrCodeMsg{Code: 5021, Message: "event request timestamp expired"}
+	ErrEventDeadLetter        = &ErrCodeMsg{Code: 5022, Message: "event dead letter error"}
+	ErrEventHandlerTimeout    = &ErrCodeMsg{Code: 5023, Message: "event handler timeout"}
+	ErrEventWsParams          = &ErrCodeMsg{Code: 5024, Message: "event websocket client params error"}
+	ErrEventWsConnect         = &ErrCodeMsg{Code: 5025, Message: "event websocket connect error"}
+	ErrEventRuleRegister      = &ErrCodeMsg{Code: 5026, Message: "event rule registered error"}
+	ErrEventShuttingDown      = &ErrCodeMsg{Code: 5027, Message: "event router is shutting down"}
+	ErrEventRateLimitParams   = &ErrCodeMsg{Code: 5028, Message: "event rate limit params error"}
 
 	ErrBotRecvMsgRegister       = &ErrCodeMsg{Code: 5100, Message: "botRecvMsg registered error"}
 	ErrBotRecvMsgMsgTypeJson    = &ErrCodeMsg{Code: 5101, Message: "botRecvMsg get msg_type error"}
 	ErrBotRecvMsgAppIDJson      = &ErrCodeMsg{Code: 5102, Message: "botRecvMsg get app_id error"}
 	ErrBotRecvMsgHandlerNoFound = &ErrCodeMsg{Code: 5103, Message: "botRecvMsg cannot find handler"}
 	ErrBotRecvMsgHandlerFailed  = &ErrCodeMsg{Code: 5104, Message: "botRecvMsg call handler failed"}
+	ErrBotRecvMsgHandlerTimeout = &ErrCodeMsg{Code: 5105, Message: "botRecvMsg call handler timeout"}
+	ErrBotRecvMsgDialog         = &ErrCodeMsg{Code: 5106, Message: "botRecvMsg dialog error"}
 
 	ErrCardParams           = &ErrCodeMsg{Code: 5200, Message: "card action callback params error"}
 	ErrCardMethodRegister   = &ErrCodeMsg{Code: 5201, Message: "card action method has not registered yet"}
@@ -106,6 +122,9 @@ var (
 	ErrCardMetaInvalid      = &ErrCodeMsg{Code: 5207, Message: "card action callback meta invalid"}
 	ErrCardHandlerIsNil     = &ErrCodeMsg{Code: 5208, Message: "card action handler not found"}
 	ErrCardHandlerFailed    = &ErrCodeMsg{Code: 5209, Message: "card action handler failed"}
+	ErrCardRequestReplayed  = &ErrCodeMsg{Code: 5210, Message: "card action callback replayed"}
+	ErrCardTimestampExpired = &ErrCodeMsg{Code: 5211, Message: "card action callback timestamp expired"}
+	ErrCardHandlerTimeout   = &ErrCodeMsg{Code: 5212, Message: "card action handler timeout"}
 
 	// 6. authentication 6000 - 6999
 	ErrValidateParams     = &ErrCodeMsg{Code: 6000, Message: "authentication-login params error"}
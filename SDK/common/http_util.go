@@ -6,6 +6,7 @@ package common
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -33,51 +34,75 @@ const (
 	HTTPCodeOK = 200
 )
 
-// DoHttpPostOApi open platform POST http
-func DoHttpPostOApi(path protocol.OpenApiPath, headers map[string]string, data interface{}) ([]byte, int, error) {
-	reqBody := new(bytes.Buffer)
-	err := json.NewEncoder(reqBody).Encode(data)
-	if err != nil {
-		return nil, 0, fmt.Errorf("jsonEncodeError[%v]", err)
-	}
+// HeaderRequestID the request id of the open platform api call
+const HeaderRequestID = "X-Request-Id"
 
-	reqURL := GetOpenPlatformHost() + string(path)
+var httpClient = &http.Client{}
 
-	return DoHttp(HTTPMethodPost, reqURL, headers, reqBody)
+// SetHTTPClient the client used to call the open platform apis, eg: set the timeout or the proxy.
+// A nil client means the default client.
+func SetHTTPClient(client *http.Client) {
+	if client == nil {
+		client = &http.Client{}
+	}
+	httpClient = client
+}
+
+// DoHttpPostOApi open platform POST http
+func DoHttpPostOApi(path protocol.OpenApiPath, headers map[string]string, data interface{}) ([]byte, int, error) {
+	return DoHttpPostOApiWithContext(context.Background(), path, headers, data)
 }
 
 // DoHttpGetOApi open platform GET http
 func DoHttpGetOApi(path protocol.OpenApiPath, headers map[string]string, params map[string]string) ([]byte, int, error) {
-	reqURL := GetOpenPlatformHost() + string(path)
+	return DoHttpGetOApiWithContext(context.Background(), path, headers, params)
+}
 
-	if params != nil && len(params) > 0 {
-		m := make(url.Values)
-		for k, v := range params {
-			m.Set(k, v)
-		}
+// DoHttpPutOApi open platform PUT http
+func DoHttpPutOApi(path protocol.OpenApiPath, headers map[string]string, data interface{}) ([]byte, int, error) {
+	return DoHttpPutOApiWithContext(context.Background(), path, headers, data)
+}
 
-		reqURL = reqURL + "?" + m.Encode()
-	}
+// DoHttpPatchApi open platform PATCH http
+func DoHttpPatchApi(path protocol.OpenApiPath, headers map[string]string, data interface{}) ([]byte, int, error) {
+	return DoHttpPatchApiWithContext(context.Background(), path, headers, data)
+}
 
-	reqBody := new(bytes.Buffer)
-	return DoHttp(HTTPMethodGet, reqURL, headers, reqBody)
+// DoHttpDeleteOApi open platform DELETE http
+func DoHttpDeleteOApi(path protocol.OpenApiPath, headers map[string]string, params map[string]string) ([]byte, int, error) {
+	return DoHttpDeleteOApiWithContext(context.Background(), path, headers, params)
 }
 
-// DoHttpPutOApi open platform PUT http
-func DoHttpPutOApi(path protocol.OpenApiPath, headers map[string]string, data interface{}) ([]byte, int, error) {
-	reqBody := new(bytes.Buffer)
-	err := json.NewEncoder(reqBody).Encode(data)
-	if err != nil {
-		return nil, 0, fmt.Errorf("jsonEncodeError[%v]", err)
-	}
+func DoHttp(method string, url string, headers map[string]string, body *bytes.Buffer) ([]byte, int, error) {
+	return DoHttpWithContext(context.Background(), method, url, headers, body)
+}
 
-	reqURL := GetOpenPlatformHost() + string(path)
+// DoHttpPostOApiWithContext open platform POST http, see DoHttpWithContext
+func DoHttpPostOApiWithContext(ctx context.Context, path protocol.OpenApiPath, headers map[string]string, data interface{}) ([]byte, int, error) {
+	return doHttpOApiWithData(ctx, HTTPMethodPost, path, headers, data)
+}
 
-	return DoHttp(http.MethodPut, reqURL, headers, reqBody)
+// DoHttpGetOApiWithContext open platform GET http, see DoHttpWithContext
+func DoHttpGetOApiWithContext(ctx context.Context, path protocol.OpenApiPath, headers map[string]string, params map[string]string) ([]byte, int, error) {
+	return doHttpOApiWithParams(ctx, HTTPMethodGet, path, headers, params)
 }
 
-// DoHttpPatchApi open platform PATCH http
-func DoHttpPatchApi(path protocol.OpenApiPath, headers map[string]string, data interface{}) ([]byte, int, error) {
+// DoHttpPutOApiWithContext open platform PUT http, see DoHttpWithContext
+func DoHttpPutOApiWithContext(ctx context.Context, path protocol.OpenApiPath, headers map[string]string, data interface{}) ([]byte, int, error) {
+	return doHttpOApiWithData(ctx, HTTPMethodPut, path, headers, data)
+}
+
+// DoHttpPatchApiWithContext open platform PATCH http, see DoHttpWithContext
+func DoHttpPatchApiWithContext(ctx context.Context, path protocol.OpenApiPath, headers map[string]string, data interface{}) ([]byte, int, error) {
+	return doHttpOApiWithData(ctx, HTTPMethodPatch, path, headers, data)
+}
+
+// DoHttpDeleteOApiWithContext open platform DELETE http, see DoHttpWithContext
+func DoHttpDeleteOApiWithContext(ctx context.Context, path protocol.OpenApiPath, headers map[string]string, params map[string]string) ([]byte, int, error) {
+	return doHttpOApiWithParams(ctx, HTTPMethodDelete, path, headers, params)
+}
+
+func doHttpOApiWithData(ctx context.Context, method string, path protocol.OpenApiPath, headers map[string]string, data interface{}) ([]byte, int, error) {
 	reqBody := new(bytes.Buffer)
 	err := json.NewEncoder(reqBody).Encode(data)
 	if err != nil {
@@ -86,11 +111,10 @@ func DoHttpPatchApi(path protocol.OpenApiPath, headers map[string]string, data i
 
 	reqURL := GetOpenPlatformHost() + string(path)
 
-	return DoHttp(http.MethodPatch, reqURL, headers, reqBody)
+	return DoHttpWithContext(ctx, method, reqURL, headers, reqBody)
 }
 
-// DoHttpDeleteOApi open platform DELETE http
-func DoHttpDeleteOApi(path protocol.OpenApiPath, headers map[string]string, params map[string]string) ([]byte, int, error) {
+func doHttpOApiWithParams(ctx context.Context, method string, path protocol.OpenApiPath, headers map[string]string, params map[string]string) ([]byte, int, error) {
 	reqURL := GetOpenPlatformHost() + string(path)
 
 	if params != nil && len(params) > 0 {
@@ -103,11 +127,13 @@ func DoHttpDeleteOApi(path protocol.OpenApiPath, headers map[string]string, para
 	}
 
 	reqBody := new(bytes.Buffer)
-	return DoHttp(http.MethodDelete, reqURL, headers, reqBody)
+	return DoHttpWithContext(ctx, method, reqURL, headers, reqBody)
 }
 
-func DoHttp(method string, url string, headers map[string]string, body *bytes.Buffer) ([]byte, int, error) {
-	req, err := http.NewRequest(method, url, body)
+// DoHttpWithContext the request is canceled when ctx is done.
+// In the event handler, the event id(see CtxKeyEventID) is sent as HeaderRequestID to correlate the calls with the event.
+func DoHttpWithContext(ctx context.Context, method string, url string, headers map[string]string, body *bytes.Buffer) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, 0, fmt.Errorf("httpNewRequestError[%v]", err)
 	}
@@ -116,12 +142,14 @@ func DoHttp(method string, url string, headers map[string]string, body *bytes.Bu
 	if headers == nil {
 		headers = map[string]string{"Content-Type": "application/json"}
 	}
+	if requestID, ok := ctx.Value(CtxKeyEventID).(string); ok && requestID != "" {
+		req.Header.Set(HeaderRequestID, requestID)
+	}
 	for k, v := range headers {
 		req.Header.Set(k, v)
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := httpClient.Do(req)
 	if resp != nil && resp.Body != nil {
 		defer resp.Body.Close()
 	}
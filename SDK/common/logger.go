@@ -38,6 +38,29 @@ func DefaultOption() *CommonLoggerOption {
 	}
 }
 
+// context keys of the event metadata, set by the event package before calling the handlers
+const (
+	CtxKeyEventID   = "botframework_event_id"
+	CtxKeyEventType = "botframework_event_type"
+	CtxKeyAppID     = "botframework_app_id"
+	CtxKeyTenantKey = "botframework_tenant_key"
+	CtxKeyChatID    = "botframework_chat_id"
+	CtxKeyOpenID    = "botframework_open_id"
+	CtxKeyMessageID = "botframework_message_id"
+)
+
+// EventLogFields context key => log field name, registered by NewCommonLogger.
+// Register them by the custom LogInterface to correlate the log with the event.
+var EventLogFields = map[string]string{
+	CtxKeyEventID:   "event_id",
+	CtxKeyEventType: "event_type",
+	CtxKeyAppID:     "app_id",
+	CtxKeyTenantKey: "tenant_key",
+	CtxKeyChatID:    "chat_id",
+	CtxKeyOpenID:    "open_id",
+	CtxKeyMessageID: "message_id",
+}
+
 type CommonLogger struct {
 	KeyMap map[string]string
 }
@@ -45,6 +68,9 @@ type CommonLogger struct {
 func NewCommonLogger() *CommonLogger {
 	log := &CommonLogger{}
 	log.RegistFieldName("request_id", "request_id")
+	for ctxKey, logKey := range EventLogFields {
+		log.RegistFieldName(ctxKey, logKey)
+	}
 
 	return log
 }
@@ -11,6 +11,7 @@ import (
 	"io"
 	"runtime"
 	"runtime/debug"
+	"time"
 )
 
 func RecoverPanic(ctx context.Context) {
@@ -22,6 +23,29 @@ func RecoverPanic(ctx context.Context) {
 	}
 }
 
+// detachedContext keeps the values of the parent context, but is not canceled with it
+type detachedContext struct {
+	context.Context
+}
+
+// DetachContext keep the values of ctx(eg: the logger fields), without its deadline and cancellation.
+// eg: the work continues after the http request finishes, or is drained on shutdown instead of being canceled.
+func DetachContext(ctx context.Context) context.Context {
+	return detachedContext{ctx}
+}
+
+func (detachedContext) Deadline() (deadline time.Time, ok bool) {
+	return
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
 func GetMd5(src io.Reader) string {
 	hash := md5.New()
 	io.Copy(hash, src)
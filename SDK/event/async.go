@@ -0,0 +1,216 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+)
+
+const (
+	DefaultAsyncWorkerNum = 16
+	DefaultAsyncQueueSize = 1024
+)
+
+type AsyncOption struct {
+	WorkerNum int // the number of goroutines handling events
+	QueueSize int // capacity of the shared queue and of each shard queue. The events are rejected when the queue is full, and the open platform will retry
+}
+
+func DefaultAsyncOption() *AsyncOption {
+	return &AsyncOption{
+		WorkerNum: DefaultAsyncWorkerNum,
+		QueueSize: DefaultAsyncQueueSize,
+	}
+}
+
+type asyncJob struct {
+	ctx       context.Context
+	appID     string
+	eventType string
+	handler   EventHandler
+	body      []byte
+}
+
+// AsyncDispatcher the verified events are put into a bounded queue and handled by a worker pool,
+// EventCallback returns immediately without waiting for the handler.
+//
+// The events without shard key are put into the shared queue, and handled by any worker.
+// The events with shard key(see EventKeyExtractorRegister) are put into the shard queue of one worker, so they are handled in order.
+type AsyncDispatcher struct {
+	option *AsyncOption
+	queue  chan *asyncJob
+	shards []chan *asyncJob
+	wg     sync.WaitGroup
+	rwMu   sync.RWMutex
+	closed bool
+}
+
+var (
+	asyncDispatcher *AsyncDispatcher
+	asyncRWMu       sync.RWMutex
+)
+
+func getAsyncDispatcher() *AsyncDispatcher {
+	asyncRWMu.RLock()
+	defer asyncRWMu.RUnlock()
+
+	return asyncDispatcher
+}
+
+// EnableAsyncDispatch demo:
+// event.EnableAsyncDispatch(event.DefaultAsyncOption())
+// defer event.ShutdownAsyncDispatch(ctx)
+func EnableAsyncDispatch(option *AsyncOption) error {
+	if option == nil {
+		option = DefaultAsyncOption()
+	}
+	if option.WorkerNum <= 0 || option.QueueSize < 0 {
+		return common.ErrEventAsyncParams.ErrorWithExtStr(
+			fmt.Sprintf("workerNum[%d]queueSize[%d]", option.WorkerNum, option.QueueSize))
+	}
+
+	asyncRWMu.Lock()
+	defer asyncRWMu.Unlock()
+
+	if asyncDispatcher != nil {
+		return common.ErrEventAsyncParams.ErrorWithExtStr("async dispatch has been enabled")
+	}
+
+	d := &AsyncDispatcher{
+		option: option,
+		queue:  make(chan *asyncJob, option.QueueSize),
+		shards: make([]chan *asyncJob, option.WorkerNum),
+	}
+	for i := 0; i < option.WorkerNum; i++ {
+		d.shards[i] = make(chan *asyncJob, option.QueueSize)
+
+		d.wg.Add(1)
+		go d.work(d.shards[i])
+	}
+
+	asyncDispatcher = d
+	return nil
+}
+
+// ShutdownAsyncDispatch stop receiving new events, and wait for the queued events to be handled.
+// The events arriving during the drain are rejected with ErrEventQueueClosed instead of being handled synchronously,
+// the dispatcher is removed after the drain. Return error if ctx is done before all events are handled.
+func ShutdownAsyncDispatch(ctx context.Context) error {
+	d := getAsyncDispatcher()
+	if d == nil {
+		return nil
+	}
+
+	err := d.shutdown(ctx)
+
+	asyncRWMu.Lock()
+	if asyncDispatcher == d {
+		asyncDispatcher = nil
+	}
+	asyncRWMu.Unlock()
+
+	return err
+}
+
+func (d *AsyncDispatcher) enqueue(ctx context.Context, appID, eventType string, handler EventHandler, body []byte) error {
+	d.rwMu.RLock()
+	defer d.rwMu.RUnlock()
+
+	if d.closed {
+		return common.ErrEventQueueClosed.ErrorWithExtStr(fmt.Sprintf("appid[%s]eventType[%s]", appID, eventType))
+	}
+
+	job := &asyncJob{
+		ctx:       common.DetachContext(ctx),
+		appID:     appID,
+		eventType: eventType,
+		handler:   handler,
+		body:      body,
+	}
+
+	queue := d.queue
+	if key := getShardKey(appID, eventType, body); key != "" {
+		queue = d.shards[shardIndex(key, len(d.shards))]
+	}
+
+	select {
+	case queue <- job:
+		return nil
+	default:
+		return common.ErrEventQueueFull.ErrorWithExtStr(
+			fmt.Sprintf("appid[%s]eventType[%s]queueSize[%d]", appID, eventType, d.option.QueueSize))
+	}
+}
+
+func (d *AsyncDispatcher) work(shard chan *asyncJob) {
+	defer d.wg.Done()
+
+	queue := d.queue
+	for queue != nil || shard != nil {
+		select {
+		case job, ok := <-queue:
+			if !ok {
+				queue = nil
+				continue
+			}
+			d.handle(job)
+		case job, ok := <-shard:
+			if !ok {
+				shard = nil
+				continue
+			}
+			d.handle(job)
+		}
+	}
+}
+
+func (d *AsyncDispatcher) handle(job *asyncJob) {
+	defer common.RecoverPanic(job.ctx)
+
+	start := time.Now()
+	err := job.handler(job.ctx, job.body)
+	if err != nil {
+		common.Logger(job.ctx).Errorf("asyncDispatch: handlerError[%v]appid[%s]eventType[%s]cost[%v]",
+			err, job.appID, job.eventType, time.Since(start))
+	}
+}
+
+func (d *AsyncDispatcher) shutdown(ctx context.Context) error {
+	d.rwMu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+		for _, shard := range d.shards {
+			close(shard)
+		}
+	}
+	d.rwMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return common.ErrEventQueueClosed.ErrorWithExtStr(fmt.Sprintf("drain queue error[%v]remain[%d]", ctx.Err(), d.remain()))
+	}
+}
+
+func (d *AsyncDispatcher) remain() int {
+	n := len(d.queue)
+	for _, shard := range d.shards {
+		n += len(shard)
+	}
+	return n
+}
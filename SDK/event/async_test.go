@@ -0,0 +1,126 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+func TestAsyncDispatch(t *testing.T) {
+	appID := "cli_test_async_dispatch"
+	initTestApp(appID)
+
+	err := event.EnableAsyncDispatch(&event.AsyncOption{WorkerNum: 1, QueueSize: 1})
+	if err != nil {
+		t.Fatalf("EnableAsyncDispatch failed: err[%v]", err)
+	}
+
+	ctx := context.Background()
+	release := make(chan struct{})
+	var handled int64
+	event.EventRegister(appID, protocol.EventTypeAddBot, func(ctx context.Context, eventBody []byte) error {
+		<-release
+		atomic.AddInt64(&handled, 1)
+		return nil
+	})
+	event.EventRegister(appID, protocol.EventTypeRemoveBot, func(ctx context.Context, eventBody []byte) error {
+		panic("handler panic")
+	})
+
+	// the panic does not stop the worker
+	_, err = event.EventCallback(ctx, newTestEventBody(appID, "async_panic", protocol.EventTypeRemoveBot), appID)
+	time.Sleep(20 * time.Millisecond)
+	if err != nil {
+		t.Errorf("AsyncDispatch panic event: err[%v]", err)
+	}
+
+	// the worker is blocked by the first event, the second one is queued, the third one is rejected
+	results := make([]error, 3)
+	for i := range results {
+		results[i] = waitEnqueue(ctx, appID, fmt.Sprintf("async_%d", i))
+	}
+	if results[0] != nil || results[1] != nil || results[2] == nil {
+		t.Errorf("AsyncDispatch bounded queue: results[%v]", results)
+	}
+	if atomic.LoadInt64(&handled) != 0 {
+		t.Errorf("AsyncDispatch: EventCallback should not wait for the handler")
+	}
+
+	close(release)
+	err = event.ShutdownAsyncDispatch(ctx)
+	if err != nil {
+		t.Errorf("ShutdownAsyncDispatch failed: err[%v]", err)
+	}
+	if atomic.LoadInt64(&handled) != 2 {
+		t.Errorf("ShutdownAsyncDispatch: handled[%d], want 2", handled)
+	}
+
+	// sync mode after shutdown
+	_, err = event.EventCallback(ctx, newTestEventBody(appID, "sync_1", protocol.EventTypeAddBot), appID)
+	if err != nil || atomic.LoadInt64(&handled) != 3 {
+		t.Errorf("sync dispatch after shutdown: handled[%d]err[%v]", handled, err)
+	}
+}
+
+// waitEnqueue the first event may still be in the queue, wait for the worker to take it
+func waitEnqueue(ctx context.Context, appID, uuid string) error {
+	_, err := event.EventCallback(ctx, newTestEventBody(appID, uuid, protocol.EventTypeAddBot), appID)
+	time.Sleep(20 * time.Millisecond)
+	return err
+}
+
+func TestAsyncDispatchDuringShutdown(t *testing.T) {
+	appID := "cli_test_async_dispatch_shutdown"
+	initTestApp(appID)
+
+	err := event.EnableAsyncDispatch(&event.AsyncOption{WorkerNum: 1, QueueSize: 1})
+	if err != nil {
+		t.Fatalf("EnableAsyncDispatch failed: err[%v]", err)
+	}
+
+	ctx := context.Background()
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var handled int64
+	event.EventRegister(appID, protocol.EventTypeAddBot, func(ctx context.Context, eventBody []byte) error {
+		started <- struct{}{}
+		<-release
+		atomic.AddInt64(&handled, 1)
+		return nil
+	})
+
+	_, err = event.EventCallback(ctx, newTestEventBody(appID, "shutdown_1", protocol.EventTypeAddBot), appID)
+	if err != nil {
+		t.Fatalf("EventCallback failed: err[%v]", err)
+	}
+	<-started
+
+	// the drain is blocked by the first event
+	done := make(chan error, 1)
+	go func() {
+		done <- event.ShutdownAsyncDispatch(ctx)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	// the event during the drain is rejected instead of being handled synchronously
+	_, err = event.EventCallback(ctx, newTestEventBody(appID, "shutdown_2", protocol.EventTypeAddBot), appID)
+	if err == nil || !strings.Contains(err.Error(), common.ErrEventQueueClosed.String()) || atomic.LoadInt64(&handled) != 0 {
+		t.Errorf("dispatch during shutdown: handled[%d]err[%v]", atomic.LoadInt64(&handled), err)
+	}
+
+	close(release)
+	if err := <-done; err != nil || atomic.LoadInt64(&handled) != 1 {
+		t.Errorf("ShutdownAsyncDispatch: handled[%d]err[%v]", atomic.LoadInt64(&handled), err)
+	}
+}
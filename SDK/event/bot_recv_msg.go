@@ -8,54 +8,192 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
+	"unicode"
 
 	"github.com/bitly/go-simplejson"
+	"github.com/larksuite/botframework-go/SDK/appconfig"
 	"github.com/larksuite/botframework-go/SDK/common"
 	"github.com/larksuite/botframework-go/SDK/protocol"
 )
 
 type HandlerBotMsg func(ctx context.Context, msg *protocol.BotRecvMsg) error
 
-// CommandHandlerManager cmd --> handler
+// CommandHandlerManager cmd --> handler, and the metadata of the commands
 type CommandHandlerManager struct {
 	mapHandler map[string]map[string]HandlerBotMsg
+	mapInfo    map[string]map[string]*CommandInfo
+	mapAlias   map[string]map[string]string // alias --> cmd
+	rwMu       sync.RWMutex
+}
+
+func newCommandHandlerManager() *CommandHandlerManager {
+	return &CommandHandlerManager{
+		mapHandler: make(map[string]map[string]HandlerBotMsg, 0),
+		mapInfo:    make(map[string]map[string]*CommandInfo, 0),
+		mapAlias:   make(map[string]map[string]string, 0),
+	}
 }
 
 func (p *CommandHandlerManager) Set(appID string, cmdName string, handler HandlerBotMsg) {
+	p.SetWithInfo(appID, cmdName, handler, nil)
+}
+
+// SetWithInfo set the handler and the metadata of the command, the metadata registered before is replaced
+func (p *CommandHandlerManager) SetWithInfo(appID string, cmdName string, handler HandlerBotMsg, info *CommandInfo) {
 	if handler == nil {
 		return
 	}
 
+	p.rwMu.Lock()
+	defer p.rwMu.Unlock()
+
 	if _, ok := p.mapHandler[appID]; !ok {
 		p.mapHandler[appID] = make(map[string]HandlerBotMsg, 0)
+		p.mapInfo[appID] = make(map[string]*CommandInfo, 0)
+		p.mapAlias[appID] = make(map[string]string, 0)
 	}
 
 	cmdName = strings.ToLower(cmdName)
 	p.mapHandler[appID][cmdName] = handler
+
+	p.deleteInfo(appID, cmdName)
+	if info != nil {
+		p.mapInfo[appID][cmdName] = info
+		for _, alias := range info.Aliases {
+			p.mapAlias[appID][strings.ToLower(alias)] = cmdName
+		}
+	}
+}
+
+// Delete return false if the command has not been registered
+func (p *CommandHandlerManager) Delete(appID string, cmdName string) bool {
+	cmdName = strings.ToLower(cmdName)
+
+	p.rwMu.Lock()
+	defer p.rwMu.Unlock()
+
+	if _, ok := p.mapHandler[appID][cmdName]; !ok {
+		return false
+	}
+	delete(p.mapHandler[appID], cmdName)
+	p.deleteInfo(appID, cmdName)
+	return true
+}
+
+func (p *CommandHandlerManager) deleteInfo(appID string, cmdName string) {
+	info, ok := p.mapInfo[appID][cmdName]
+	if !ok {
+		return
+	}
+	for _, alias := range info.Aliases {
+		alias = strings.ToLower(alias)
+		if p.mapAlias[appID][alias] == cmdName {
+			delete(p.mapAlias[appID], alias)
+		}
+	}
+	delete(p.mapInfo[appID], cmdName)
 }
 
+// Replace return false if the command has not been registered, the metadata is kept
+func (p *CommandHandlerManager) Replace(appID string, cmdName string, handler HandlerBotMsg) bool {
+	if handler == nil {
+		return false
+	}
+	cmdName = strings.ToLower(cmdName)
+
+	p.rwMu.Lock()
+	defer p.rwMu.Unlock()
+
+	if _, ok := p.mapHandler[appID][cmdName]; !ok {
+		return false
+	}
+	p.mapHandler[appID][cmdName] = handler
+	return true
+}
+
+// Get the handler of the command or the alias
 func (p *CommandHandlerManager) Get(appID string, cmdName string) (HandlerBotMsg, error) {
 	cmdName = strings.ToLower(cmdName)
 
+	p.rwMu.RLock()
+	defer p.rwMu.RUnlock()
+
 	if _, ok := p.mapHandler[appID]; !ok {
 		return nil, fmt.Errorf("botRecvMsg appid[%s] has not been registered", appID)
 	}
-	if _, ok := p.mapHandler[appID][cmdName]; !ok {
-		return nil, fmt.Errorf("botRecvMsg cmdName[%s] has not been registered in appid[%s]", cmdName, appID)
+	if handler, ok := p.mapHandler[appID][cmdName]; ok {
+		return handler, nil
+	}
+	if name, ok := p.mapAlias[appID][cmdName]; ok {
+		return p.mapHandler[appID][name], nil
 	}
 
-	return p.mapHandler[appID][cmdName], nil
+	return nil, fmt.Errorf("botRecvMsg cmdName[%s] has not been registered in appid[%s]", cmdName, appID)
 }
 
-var cmdHandler *CommandHandlerManager
+// GetInfo get the metadata of the command or the alias, return nil if the command is registered without metadata
+func (p *CommandHandlerManager) GetInfo(appID string, cmdName string) *CommandInfo {
+	cmdName = strings.ToLower(cmdName)
+
+	p.rwMu.RLock()
+	defer p.rwMu.RUnlock()
 
-func init() {
-	cmdHandler = &CommandHandlerManager{mapHandler: make(map[string]map[string]HandlerBotMsg, 0)}
+	if _, ok := p.mapHandler[appID][cmdName]; ok {
+		return p.mapInfo[appID][cmdName]
+	}
+	return p.mapInfo[appID][p.mapAlias[appID][cmdName]]
+}
+
+// GetInfos get the metadata of all commands of the app, sorted by the name.
+// The command registered without metadata has only the name.
+func (p *CommandHandlerManager) GetInfos(appID string) []*CommandInfo {
+	p.rwMu.RLock()
+	defer p.rwMu.RUnlock()
+
+	infos := make([]*CommandInfo, 0, len(p.mapHandler[appID]))
+	for name := range p.mapHandler[appID] {
+		info, ok := p.mapInfo[appID][name]
+		if !ok {
+			info = &CommandInfo{Name: name}
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
 }
 
 // BotRecvMsgRegister appid+cmd --> handler
 func BotRecvMsgRegister(appID string, cmdName string, handler HandlerBotMsg) error {
+	return defaultRouter.BotRecvMsgRegister(appID, cmdName, handler)
+}
+
+// BotRecvMsgRegisterWithInfo appid+cmd --> handler, the metadata is shown by the help command
+func BotRecvMsgRegisterWithInfo(appID string, cmdName string, handler HandlerBotMsg, info *CommandInfo) error {
+	return defaultRouter.BotRecvMsgRegisterWithInfo(appID, cmdName, handler, info)
+}
+
+// BotRecvMsgUnregister remove the handler of the command
+func BotRecvMsgUnregister(appID string, cmdName string) error {
+	return defaultRouter.BotRecvMsgUnregister(appID, cmdName)
+}
+
+// BotRecvMsgReplace hot swap the handler of the registered command
+func BotRecvMsgReplace(appID string, cmdName string, handler HandlerBotMsg) error {
+	return defaultRouter.BotRecvMsgReplace(appID, cmdName, handler)
+}
+
+// BotRecvMsgHandler callback botRecvMsg
+func BotRecvMsgHandler(ctx context.Context, data []byte) error {
+	return defaultRouter.BotRecvMsgHandler(ctx, data)
+}
+
+// BotRecvMsgRegister appid+cmd --> handler
+func (r *Router) BotRecvMsgRegister(appID string, cmdName string, handler HandlerBotMsg) error {
 	if appID == "" || cmdName == "" {
 		return common.ErrBotRecvMsgRegister.ErrorWithExtStr("appID/cmdName is empty")
 	}
@@ -63,12 +201,63 @@ func BotRecvMsgRegister(appID string, cmdName string, handler HandlerBotMsg) err
 		return common.ErrBotRecvMsgRegister.ErrorWithExtStr("action handler is nil")
 	}
 
-	cmdHandler.Set(appID, cmdName, handler)
+	r.cmdHandler.Set(appID, cmdName, handler)
 	return nil
 }
 
-// BotRecvMsgHandler callback botRecvMsg
-func BotRecvMsgHandler(ctx context.Context, data []byte) error {
+// BotRecvMsgRegisterWithInfo appid+cmd --> handler, the metadata is shown by the help command.
+// The aliases of the metadata are dispatched to the handler too.
+func (r *Router) BotRecvMsgRegisterWithInfo(appID string, cmdName string, handler HandlerBotMsg, info *CommandInfo) error {
+	if appID == "" || cmdName == "" {
+		return common.ErrBotRecvMsgRegister.ErrorWithExtStr("appID/cmdName is empty")
+	}
+	if handler == nil {
+		return common.ErrBotRecvMsgRegister.ErrorWithExtStr("action handler is nil")
+	}
+
+	if info != nil {
+		copied := *info
+		copied.Name = strings.ToLower(cmdName)
+		info = &copied
+	}
+	r.cmdHandler.SetWithInfo(appID, cmdName, handler, info)
+	return nil
+}
+
+// CommandInfos get the metadata of the registered commands, sorted by the name
+func (r *Router) CommandInfos(appID string) []*CommandInfo {
+	return r.cmdHandler.GetInfos(appID)
+}
+
+// BotRecvMsgUnregister remove the handler of the command
+func (r *Router) BotRecvMsgUnregister(appID string, cmdName string) error {
+	if appID == "" || cmdName == "" {
+		return common.ErrBotRecvMsgRegister.ErrorWithExtStr("appID/cmdName is empty")
+	}
+
+	if !r.cmdHandler.Delete(appID, cmdName) {
+		return common.ErrBotRecvMsgHandlerNoFound.ErrorWithExtStr(fmt.Sprintf("appid[%s]cmdName[%s]", appID, cmdName))
+	}
+	return nil
+}
+
+// BotRecvMsgReplace hot swap the handler of the registered command, return error if the command has not been registered
+func (r *Router) BotRecvMsgReplace(appID string, cmdName string, handler HandlerBotMsg) error {
+	if appID == "" || cmdName == "" {
+		return common.ErrBotRecvMsgRegister.ErrorWithExtStr("appID/cmdName is empty")
+	}
+	if handler == nil {
+		return common.ErrBotRecvMsgRegister.ErrorWithExtStr("action handler is nil")
+	}
+
+	if !r.cmdHandler.Replace(appID, cmdName, handler) {
+		return common.ErrBotRecvMsgHandlerNoFound.ErrorWithExtStr(fmt.Sprintf("appid[%s]cmdName[%s]", appID, cmdName))
+	}
+	return nil
+}
+
+// BotRecvMsgHandler callback botRecvMsg, dispatch the message by the commands of the router
+func (r *Router) BotRecvMsgHandler(ctx context.Context, data []byte) error {
 	// get msg_type and app_id
 	jsonMsg, err := simplejson.NewJson(data)
 	if err != nil {
@@ -120,7 +309,19 @@ func BotRecvMsgHandler(ctx context.Context, data []byte) error {
 		msg.OpenMessageID = msgEvent.OpenMessageID
 		msg.OriData = msgEvent
 
-		textWithoutAtBot = ""
+		// the post is flattened to text, so the commands can be sent by rich text
+		content, err := ParsePostText(msgEvent.Text, msgEvent.ImageKeys)
+		if err != nil {
+			common.Logger(ctx).Warnf("botRecvMsg: parsePostError[%v]messageid[%s]", err, msgEvent.OpenMessageID)
+			break
+		}
+		msg.RichText = &protocol.RichTextForm{Title: msgEvent.Title, Content: content}
+
+		appConf, _ := appconfig.GetConfig(appID)
+		textWithoutAtBot, err = postTextWithoutAtBot(msgEvent, content, appConf.BotOpenID)
+		if err != nil {
+			common.Logger(ctx).Warnf("botRecvMsg: parsePostError[%v]messageid[%s]", err, msgEvent.OpenMessageID)
+		}
 	case protocol.EventMsgTypeImage:
 		msgEvent := &protocol.ImageMsgEvent{}
 		err := json.Unmarshal(data, msgEvent)
@@ -159,38 +360,52 @@ func BotRecvMsgHandler(ctx context.Context, data []byte) error {
 
 	msg.TextParam = textWithoutAtBot
 
-	//get cmd
-	var cmd string
-	s := strings.Split(strings.Trim(msg.TextParam, " "), " ")
-	if len(s) > 1 {
-		cmd = strings.ToLower(s[0])
-		msg.TextParam = strings.Trim(strings.Join(s[1:], " "), " ")
-	} else if len(s) > 0 {
-		cmd = strings.ToLower(s[0])
-		msg.TextParam = ""
-	} else {
-		cmd = protocol.CmdDefault
+	// dispatch in the order: the active dialog of the sender, the command, the matchers, the default command
+	cmd, handler := r.dialogHandler(ctx, &msg)
+	if handler == nil {
+		cmd, handler = r.commandHandler(appID, &msg)
 	}
-
-	//get handler
-	handler, err := cmdHandler.Get(appID, cmd)
-	if err != nil {
-		if protocol.CmdDefault != cmd {
-			cmd = protocol.CmdDefault
-			msg.TextParam = textWithoutAtBot
-
-			handler, err = cmdHandler.Get(appID, cmd)
-		}
-
+	if handler == nil {
+		cmd, handler = r.matcherHandler(ctx, &msg)
+	}
+	if handler == nil {
+		cmd = protocol.CmdDefault
+		handler, err = r.cmdHandler.Get(appID, cmd)
 		if err != nil {
 			return common.ErrBotRecvMsgHandlerNoFound.ErrorWithExtErr(err)
 		}
 	}
 
+	handler = handlerTimeout.WrapBotMsg(appID, cmd, handler)
+	handler = middlewareManager.WrapBotMsg(appID, handler)
 	err = handler(ctx, &msg)
 	if err != nil {
+		if isTimeout(err) {
+			return err
+		}
 		return common.ErrBotRecvMsgHandlerFailed.ErrorWithExtErr(err)
 	}
 
 	return nil
 }
+
+// commandHandler get the handler by the first word of msg.TextParam, and set msg.TextParam to the text after the command.
+// Return nil if the command has not been registered.
+func (r *Router) commandHandler(appID string, msg *protocol.BotRecvMsg) (string, HandlerBotMsg) {
+	//get cmd, separated by any space, eg: the new line of the post
+	text := strings.TrimSpace(msg.TextParam)
+	cmd, textParam := text, ""
+	if i := strings.IndexFunc(text, unicode.IsSpace); i >= 0 {
+		cmd, textParam = text[:i], strings.TrimSpace(text[i:])
+	}
+	cmd = strings.ToLower(cmd)
+
+	//get handler
+	handler, err := r.cmdHandler.Get(appID, cmd)
+	if err != nil {
+		return "", nil
+	}
+
+	msg.TextParam = textParam
+	return cmd, handler
+}
@@ -6,10 +6,12 @@ package event
 
 import (
 	"context"
+	"crypto/hmac"
 	"crypto/sha1"
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/larksuite/botframework-go/SDK/appconfig"
 	"github.com/larksuite/botframework-go/SDK/common"
@@ -21,6 +23,14 @@ type ActionMethod func(ctx context.Context, cardCallback *protocol.CardCallbackF
 type ActionHandlerManager struct {
 	mapHandler map[string]map[string]ActionMethod // appID => method => func
 	ignoreSign map[string]bool
+	rwMu       sync.RWMutex
+}
+
+func newActionHandlerManager() *ActionHandlerManager {
+	return &ActionHandlerManager{
+		mapHandler: make(map[string]map[string]ActionMethod, 0),
+		ignoreSign: make(map[string]bool, 0),
+	}
 }
 
 func (a *ActionHandlerManager) Set(appID string, method string, v ActionMethod) {
@@ -28,6 +38,9 @@ func (a *ActionHandlerManager) Set(appID string, method string, v ActionMethod)
 		return
 	}
 
+	a.rwMu.Lock()
+	defer a.rwMu.Unlock()
+
 	if m, ok := a.mapHandler[appID]; !ok || m == nil {
 		a.mapHandler[appID] = make(map[string]ActionMethod, 0)
 	}
@@ -35,7 +48,52 @@ func (a *ActionHandlerManager) Set(appID string, method string, v ActionMethod)
 	a.mapHandler[appID][method] = v
 }
 
+// Delete return false if the method has not been registered
+func (a *ActionHandlerManager) Delete(appID string, method string) bool {
+	a.rwMu.Lock()
+	defer a.rwMu.Unlock()
+
+	if _, ok := a.mapHandler[appID][method]; !ok {
+		return false
+	}
+	delete(a.mapHandler[appID], method)
+	return true
+}
+
+// Replace return false if the method has not been registered
+func (a *ActionHandlerManager) Replace(appID string, method string, v ActionMethod) bool {
+	if v == nil {
+		return false
+	}
+
+	a.rwMu.Lock()
+	defer a.rwMu.Unlock()
+
+	if _, ok := a.mapHandler[appID][method]; !ok {
+		return false
+	}
+	a.mapHandler[appID][method] = v
+	return true
+}
+
+func (a *ActionHandlerManager) SetIgnoreSign(appID string, ignore bool) {
+	a.rwMu.Lock()
+	defer a.rwMu.Unlock()
+
+	a.ignoreSign[appID] = ignore
+}
+
+func (a *ActionHandlerManager) IsIgnoreSign(appID string) bool {
+	a.rwMu.RLock()
+	defer a.rwMu.RUnlock()
+
+	return a.ignoreSign[appID]
+}
+
 func (a *ActionHandlerManager) Get(appID string, method string) (ActionMethod, error) {
+	a.rwMu.RLock()
+	defer a.rwMu.RUnlock()
+
 	if _, ok := a.mapHandler[appID]; !ok {
 		return nil, fmt.Errorf("getCardActionHandler appid[%s] has not been registered", appID)
 	}
@@ -46,20 +104,33 @@ func (a *ActionHandlerManager) Get(appID string, method string) (ActionMethod, e
 	return a.mapHandler[appID][method], nil
 }
 
-var cardHandler *ActionHandlerManager
+func IgnoreSign(appid string, ignore bool) {
+	defaultRouter.IgnoreSign(appid, ignore)
+}
+
+func CardRegister(appID string, method string, handler ActionMethod) error {
+	return defaultRouter.CardRegister(appID, method, handler)
+}
 
-func init() {
-	cardHandler = &ActionHandlerManager{
-		mapHandler: make(map[string]map[string]ActionMethod, 0),
-		ignoreSign: make(map[string]bool, 0),
-	}
+// CardUnregister remove the handler of the method
+func CardUnregister(appID string, method string) error {
+	return defaultRouter.CardUnregister(appID, method)
 }
 
-func IgnoreSign(appid string, ignore bool) {
-	cardHandler.ignoreSign[appid] = ignore
+// CardReplace hot swap the handler of the registered method
+func CardReplace(appID string, method string, handler ActionMethod) error {
+	return defaultRouter.CardReplace(appID, method, handler)
 }
 
-func CardRegister(appID string, method string, handler ActionMethod) error {
+func CardCallBack(ctx context.Context, appID string, header map[string]string, body []byte) (*protocol.CardForm, string, error) {
+	return defaultRouter.CardCallBack(ctx, appID, header, body)
+}
+
+func (r *Router) IgnoreSign(appid string, ignore bool) {
+	r.cardHandler.SetIgnoreSign(appid, ignore)
+}
+
+func (r *Router) CardRegister(appID string, method string, handler ActionMethod) error {
 	if appID == "" || method == "" {
 		return common.ErrCardMethodRegister.ErrorWithExtStr("method/appID is empty")
 	}
@@ -67,19 +138,61 @@ func CardRegister(appID string, method string, handler ActionMethod) error {
 		return common.ErrCardMethodRegister.ErrorWithExtStr("action handler is nil")
 	}
 
-	cardHandler.Set(appID, method, handler)
+	r.cardHandler.Set(appID, method, handler)
 
 	return nil
 }
 
-func CardCallBack(ctx context.Context, appID string, header map[string]string, body []byte) (*protocol.CardForm, string, error) {
+// CardUnregister remove the handler of the method
+func (r *Router) CardUnregister(appID string, method string) error {
+	if appID == "" || method == "" {
+		return common.ErrCardMethodRegister.ErrorWithExtStr("method/appID is empty")
+	}
+
+	if !r.cardHandler.Delete(appID, method) {
+		return common.ErrCardMethodRegister.ErrorWithExtStr(fmt.Sprintf("appid[%s]method[%s]", appID, method))
+	}
+	return nil
+}
+
+// CardReplace hot swap the handler of the registered method, return error if the method has not been registered
+func (r *Router) CardReplace(appID string, method string, handler ActionMethod) error {
+	if appID == "" || method == "" {
+		return common.ErrCardMethodRegister.ErrorWithExtStr("method/appID is empty")
+	}
+	if handler == nil {
+		return common.ErrCardMethodRegister.ErrorWithExtStr("action handler is nil")
+	}
+
+	if !r.cardHandler.Replace(appID, method, handler) {
+		return common.ErrCardMethodRegister.ErrorWithExtStr(fmt.Sprintf("appid[%s]method[%s]", appID, method))
+	}
+	return nil
+}
+
+// CardCallBack dispatch the card action by the methods of the router
+func (r *Router) CardCallBack(ctx context.Context, appID string, header map[string]string, body []byte) (*protocol.CardForm, string, error) {
+	if !r.drainer.Acquire() {
+		return nil, "", common.ErrEventShuttingDown.ErrorWithExtStr(fmt.Sprintf("appid[%s]", appID))
+	}
+	defer r.drainer.Release()
+
+	record := newRecord(RecordKindCard, appID, header, string(body))
+
+	card, challenge, err := r.cardCallBack(ctx, appID, header, body, record)
+	record.finish(ctx, err)
+
+	return card, challenge, err
+}
+
+func (r *Router) cardCallBack(ctx context.Context, appID string, header map[string]string, body []byte, record *Record) (*protocol.CardForm, string, error) {
 	// check params
 	if appID == "" || len(header) == 0 || len(body) == 0 {
 		return nil, "", common.ErrCardParams.ErrorWithExtStr("callBack params is empty")
 	}
 
 	// check init
-	if cardHandler == nil {
+	if r.cardHandler == nil {
 		return nil, "", common.ErrCardManagerNotInit.Error()
 	}
 
@@ -112,19 +225,28 @@ func CardCallBack(ctx context.Context, appID string, header map[string]string, b
 		return nil, "", common.ErrJsonUnmarshal.ErrorWithExtErr(fmt.Errorf("card callback error[%v]", err))
 	}
 
-	// check signature
-	if !cardHandler.ignoreSign[appID] {
+	// check signature, the signature of the redacted record can't be verified
+	if !r.cardHandler.IsIgnoreSign(appID) && !isRedactedReplay(ctx) {
 		err = verifySignature(ctx, appConf.VerifyToken, header, body)
 		if err != nil {
 			return nil, "", common.ErrCardSignatureInvalid.Error()
 		}
 	}
 
+	// check replay
+	err = checkCardReplay(ctx, appID, header)
+	if err != nil {
+		return nil, "", err
+	}
+
 	var ok bool
 	var method string
 	if method, ok = callback.Action.Value["method"]; !ok {
 		return nil, "", common.ErrCardWithoutMethod.ErrorWithExtErr(err)
 	}
+	if record != nil {
+		record.Method = method
+	}
 	if _, ok := callback.Action.Value["sid"]; !ok {
 		return nil, "", common.ErrCardWithoutSessionID.ErrorWithExtErr(err)
 	}
@@ -140,7 +262,7 @@ func CardCallBack(ctx context.Context, appID string, header map[string]string, b
 		}
 	}
 
-	handler, err := cardHandler.Get(appID, method)
+	handler, err := r.cardHandler.Get(appID, method)
 	if err != nil {
 		return nil, "", common.ErrCardMethodRegister.ErrorWithExtErr(err)
 	}
@@ -148,8 +270,14 @@ func CardCallBack(ctx context.Context, appID string, header map[string]string, b
 		return nil, "", common.ErrCardHandlerIsNil.ErrorWithExtStr(fmt.Sprintf("method[%s]", method))
 	}
 
+	ctx = withEventMeta(ctx, newCardMeta(appID, callback))
+	handler = handlerTimeout.WrapAction(appID, method, handler)
+	handler = middlewareManager.WrapAction(appID, handler)
 	card, err := handler(ctx, callback)
 	if err != nil {
+		if isTimeout(err) {
+			return nil, "", err
+		}
 		return nil, "", common.ErrCardHandlerFailed.ErrorWithExtErr(err)
 	}
 
@@ -158,12 +286,13 @@ func CardCallBack(ctx context.Context, appID string, header map[string]string, b
 
 // check action Signature
 func verifySignature(ctx context.Context, verifyToken string, header map[string]string, body []byte) error {
-	timestamp := header["X-Lark-Request-Timestamp"]
-	nonce := header["X-Lark-Request-Nonce"]
-	sig := header["X-Lark-Signature"]
+	timestamp := header[HeaderRequestTimestamp]
+	nonce := header[HeaderRequestNonce]
+	sig := header[HeaderSignature]
 
+	// constant time comparison
 	targetSig := genPostRequestSignature(nonce, timestamp, string(body), verifyToken)
-	if sig == targetSig {
+	if hmac.Equal([]byte(sig), []byte(targetSig)) {
 		return nil
 	}
 
@@ -0,0 +1,521 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/message"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+type ArgType string
+
+const (
+	ArgTypeString   ArgType = "string"
+	ArgTypeInt      ArgType = "int"
+	ArgTypeBool     ArgType = "bool"
+	ArgTypeDuration ArgType = "duration" // time.ParseDuration, eg: 30s, 1h30m
+	ArgTypeMention  ArgType = "mention"  // <at open_id="ou_xxx">@name</at> or ou_xxx, the value is the open id
+)
+
+// HandlerCommand the handler of the command registered by CommandRegister
+type HandlerCommand func(ctx context.Context, msg *protocol.BotRecvMsg, args *CommandArgs) error
+
+// CommandArg the positional argument
+type CommandArg struct {
+	Name     string
+	Type     ArgType // empty means ArgTypeString
+	Required bool
+	Default  string // parsed by Type when the argument is not given
+	Usage    string
+}
+
+// CommandFlag --name value, --name=value, -short value. The bool flag is true without value: --name
+type CommandFlag struct {
+	Name     string
+	Short    string
+	Type     ArgType // empty means ArgTypeString
+	Required bool
+	Default  string // parsed by Type when the flag is not given
+	Usage    string
+}
+
+// Command the command with typed arguments and flags, demo:
+//
+// event.CommandRegister(appID, &event.Command{
+// 	Name: "remind",
+// 	Args: []*event.CommandArg{
+// 		{Name: "user", Type: event.ArgTypeMention, Required: true},
+// 		{Name: "text", Required: true},
+// 	},
+// 	Flags: []*event.CommandFlag{
+// 		{Name: "after", Short: "a", Type: event.ArgTypeDuration, Default: "10m"},
+// 	},
+// 	Handler: func(ctx context.Context, msg *protocol.BotRecvMsg, args *event.CommandArgs) error {
+// 		// remind <at open_id="ou_xxx">@Tom</at> "submit the report" --after 1h
+// 		// args.Mention("user"), args.String("text"), args.Duration("after")
+// 		return nil
+// 	},
+// })
+//
+// The text is split by spaces, the quoted text("..." '...' “...”) is one argument.
+// The usage error is replied to the chat if the text can't be parsed, and the handler is not called.
+type Command struct {
+	Name    string
+	Args    []*CommandArg
+	Flags   []*CommandFlag
+	Rest    bool // allow the extra positional arguments, see CommandArgs.Rest
+	Handler HandlerCommand
+	Info    *CommandInfo // the metadata shown by the help command, Info.Usage is Usage() if empty
+
+	// reply the usage error, nil means sending the text of the error to the chat
+	UsageReply func(ctx context.Context, msg *protocol.BotRecvMsg, err *CommandUsageError) error
+}
+
+// CommandUsageError the text of the command can't be parsed
+type CommandUsageError struct {
+	Command *Command
+	Reason  string
+}
+
+func (e *CommandUsageError) Error() string {
+	return fmt.Sprintf("%s\nusage: %s", e.Reason, e.Command.Usage())
+}
+
+// Usage eg: remind <user> <text> [--after|-a duration]
+func (c *Command) Usage() string {
+	parts := []string{c.Name}
+	for _, arg := range c.Args {
+		if arg.Required {
+			parts = append(parts, "<"+arg.Name+">")
+		} else {
+			parts = append(parts, "["+arg.Name+"]")
+		}
+	}
+	if c.Rest {
+		parts = append(parts, "...")
+	}
+	for _, flag := range c.Flags {
+		name := "--" + flag.Name
+		if flag.Short != "" {
+			name += "|-" + flag.Short
+		}
+		if flag.argType() != ArgTypeBool {
+			name += " " + string(flag.argType())
+		}
+		if flag.Required {
+			parts = append(parts, name)
+		} else {
+			parts = append(parts, "["+name+"]")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// CommandArgs the parsed arguments and flags, the getters return the zero value if the name is not given and has no default
+type CommandArgs struct {
+	values map[string]interface{}
+	given  map[string]bool
+	Rest   []string // the extra positional arguments if Command.Rest is true
+}
+
+// Has return true if the argument/flag is given in the text
+func (a *CommandArgs) Has(name string) bool {
+	return a.given[name]
+}
+
+func (a *CommandArgs) String(name string) string {
+	v, _ := a.values[name].(string)
+	return v
+}
+
+func (a *CommandArgs) Int(name string) int64 {
+	v, _ := a.values[name].(int64)
+	return v
+}
+
+func (a *CommandArgs) Bool(name string) bool {
+	v, _ := a.values[name].(bool)
+	return v
+}
+
+func (a *CommandArgs) Duration(name string) time.Duration {
+	v, _ := a.values[name].(time.Duration)
+	return v
+}
+
+// Mention return the open id of the mentioned user
+func (a *CommandArgs) Mention(name string) string {
+	return a.String(name)
+}
+
+// CommandRegister register the command by BotRecvMsgRegister, the text after the command name is parsed to CommandArgs
+func CommandRegister(appID string, cmd *Command) error {
+	return defaultRouter.CommandRegister(appID, cmd)
+}
+
+// CommandRegister register the command by BotRecvMsgRegister, the text after the command name is parsed to CommandArgs
+func (r *Router) CommandRegister(appID string, cmd *Command) error {
+	if cmd == nil {
+		return common.ErrBotRecvMsgRegister.ErrorWithExtStr("command is nil")
+	}
+	if cmd.Handler == nil {
+		return common.ErrBotRecvMsgRegister.ErrorWithExtStr("command handler is nil")
+	}
+	err := cmd.validate()
+	if err != nil {
+		return common.ErrBotRecvMsgRegister.ErrorWithExtErr(err)
+	}
+
+	info := &CommandInfo{}
+	if cmd.Info != nil {
+		*info = *cmd.Info
+	}
+	if info.Usage == "" {
+		info.Usage = cmd.Usage()
+	}
+	return r.BotRecvMsgRegisterWithInfo(appID, cmd.Name, cmd.handle, info)
+}
+
+func (c *Command) validate() error {
+	names := make(map[string]bool)
+	optional := false
+	for _, arg := range c.Args {
+		if arg.Name == "" || names[arg.Name] {
+			return fmt.Errorf("command[%s] arg name[%s] is empty or duplicate", c.Name, arg.Name)
+		}
+		names[arg.Name] = true
+
+		if arg.Required && optional {
+			return fmt.Errorf("command[%s] required arg[%s] follows optional arg", c.Name, arg.Name)
+		}
+		optional = !arg.Required
+
+		if err := checkDefault(arg.argType(), arg.Default); err != nil {
+			return fmt.Errorf("command[%s] arg[%s] %v", c.Name, arg.Name, err)
+		}
+	}
+
+	shorts := make(map[string]bool)
+	for _, flag := range c.Flags {
+		if flag.Name == "" || names[flag.Name] {
+			return fmt.Errorf("command[%s] flag name[%s] is empty or duplicate", c.Name, flag.Name)
+		}
+		names[flag.Name] = true
+
+		if flag.Short != "" {
+			if shorts[flag.Short] {
+				return fmt.Errorf("command[%s] flag short name[%s] is duplicate", c.Name, flag.Short)
+			}
+			shorts[flag.Short] = true
+		}
+
+		if err := checkDefault(flag.argType(), flag.Default); err != nil {
+			return fmt.Errorf("command[%s] flag[%s] %v", c.Name, flag.Name, err)
+		}
+	}
+	return nil
+}
+
+func checkDefault(argType ArgType, value string) error {
+	switch argType {
+	case ArgTypeString, ArgTypeInt, ArgTypeBool, ArgTypeDuration, ArgTypeMention:
+	default:
+		return fmt.Errorf("type[%s] invalid", argType)
+	}
+	if value == "" {
+		return nil
+	}
+
+	_, err := parseArgValue(argType, value)
+	if err != nil {
+		return fmt.Errorf("default[%s] invalid", value)
+	}
+	return nil
+}
+
+func (c *Command) handle(ctx context.Context, msg *protocol.BotRecvMsg) error {
+	args, err := c.Parse(msg.TextParam)
+	if err != nil {
+		usageErr, ok := err.(*CommandUsageError)
+		if !ok {
+			return err
+		}
+
+		common.Logger(ctx).Infof("command: usageError[%s]cmd[%s]text[%s]", usageErr.Reason, c.Name, msg.TextParam)
+		if c.UsageReply != nil {
+			return c.UsageReply(ctx, msg, usageErr)
+		}
+		return replyText(ctx, msg.TenantKey, msg.AppID, msg.OpenChatID, msg.OpenID, msg.OpenMessageID, usageErr.Error())
+	}
+
+	return c.Handler(ctx, msg, args)
+}
+
+// Parse parse the text after the command name, return *CommandUsageError if the text is invalid
+func (c *Command) Parse(text string) (*CommandArgs, error) {
+	tokens, err := splitCommandText(text)
+	if err != nil {
+		return nil, &CommandUsageError{Command: c, Reason: err.Error()}
+	}
+
+	args := &CommandArgs{
+		values: make(map[string]interface{}),
+		given:  make(map[string]bool),
+	}
+	positional := make([]string, 0, len(tokens))
+	flagDone := false
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i].text
+		// the quoted token is literal, eg: "-1" or "--name" as a value
+		if flagDone || tokens[i].quoted || !isFlagToken(token) {
+			positional = append(positional, token)
+			continue
+		}
+		if token == "--" {
+			flagDone = true
+			continue
+		}
+
+		name := strings.TrimLeft(token, "-")
+		value, hasValue := "", false
+		if idx := strings.Index(name, "="); idx >= 0 {
+			name, value, hasValue = name[:idx], name[idx+1:], true
+		}
+
+		flag := c.findFlag(name, !strings.HasPrefix(token, "--"))
+		if flag == nil {
+			return nil, &CommandUsageError{Command: c, Reason: fmt.Sprintf("unknown flag: %s", token)}
+		}
+		if !hasValue {
+			if flag.argType() == ArgTypeBool {
+				value = "true"
+			} else if i+1 < len(tokens) {
+				i++
+				value = tokens[i].text
+			} else {
+				return nil, &CommandUsageError{Command: c, Reason: fmt.Sprintf("flag --%s needs a value", flag.Name)}
+			}
+		}
+
+		v, err := parseArgValue(flag.argType(), value)
+		if err != nil {
+			return nil, &CommandUsageError{Command: c, Reason: fmt.Sprintf("flag --%s: %v", flag.Name, err)}
+		}
+		args.values[flag.Name] = v
+		args.given[flag.Name] = true
+	}
+
+	for i, arg := range c.Args {
+		if i >= len(positional) {
+			if arg.Required {
+				return nil, &CommandUsageError{Command: c, Reason: fmt.Sprintf("missing argument: <%s>", arg.Name)}
+			}
+			break
+		}
+
+		v, err := parseArgValue(arg.argType(), positional[i])
+		if err != nil {
+			return nil, &CommandUsageError{Command: c, Reason: fmt.Sprintf("argument <%s>: %v", arg.Name, err)}
+		}
+		args.values[arg.Name] = v
+		args.given[arg.Name] = true
+	}
+	if len(positional) > len(c.Args) {
+		if !c.Rest {
+			return nil, &CommandUsageError{Command: c, Reason: fmt.Sprintf("too many arguments: %s", strings.Join(positional[len(c.Args):], " "))}
+		}
+		args.Rest = positional[len(c.Args):]
+	}
+
+	for _, flag := range c.Flags {
+		if args.given[flag.Name] {
+			continue
+		}
+		if flag.Required {
+			return nil, &CommandUsageError{Command: c, Reason: fmt.Sprintf("missing flag: --%s", flag.Name)}
+		}
+		setDefault(args, flag.Name, flag.argType(), flag.Default)
+	}
+	for _, arg := range c.Args {
+		if !args.given[arg.Name] {
+			setDefault(args, arg.Name, arg.argType(), arg.Default)
+		}
+	}
+
+	return args, nil
+}
+
+func (c *Command) findFlag(name string, short bool) *CommandFlag {
+	for _, flag := range c.Flags {
+		if (short && flag.Short == name) || (!short && flag.Name == name) {
+			return flag
+		}
+	}
+	return nil
+}
+
+func setDefault(args *CommandArgs, name string, argType ArgType, value string) {
+	if value == "" {
+		return
+	}
+	// the default is checked by CommandRegister
+	v, err := parseArgValue(argType, value)
+	if err == nil {
+		args.values[name] = v
+	}
+}
+
+func (a *CommandArg) argType() ArgType {
+	if a.Type == "" {
+		return ArgTypeString
+	}
+	return a.Type
+}
+
+func (f *CommandFlag) argType() ArgType {
+	if f.Type == "" {
+		return ArgTypeString
+	}
+	return f.Type
+}
+
+// isFlagToken -n, --name, but not the negative number
+func isFlagToken(token string) bool {
+	if len(token) < 2 || token[0] != '-' {
+		return false
+	}
+	_, err := strconv.ParseFloat(token, 64)
+	return err != nil
+}
+
+var mentionRegexp = regexp.MustCompile(`^<at\s+open_id="([^"]*)"[^>]*>.*</at>$`)
+
+func parseArgValue(argType ArgType, value string) (interface{}, error) {
+	switch argType {
+	case ArgTypeInt:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not an integer", value)
+		}
+		return v, nil
+	case ArgTypeBool:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a bool", value)
+		}
+		return v, nil
+	case ArgTypeDuration:
+		v, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a duration", value)
+		}
+		return v, nil
+	case ArgTypeMention:
+		if match := mentionRegexp.FindStringSubmatch(value); match != nil && match[1] != "" {
+			return match[1], nil
+		}
+		if strings.HasPrefix(value, "ou_") {
+			return value, nil
+		}
+		return nil, fmt.Errorf("%s is not a mentioned user", value)
+	default:
+		return value, nil
+	}
+}
+
+var commandQuotes = map[rune]rune{'"': '"', '\'': '\'', '“': '”', '‘': '’'}
+
+type commandToken struct {
+	text   string
+	quoted bool // the token starts with a quote
+}
+
+// splitCommandText split the text by spaces, the quoted text and the mention(<at ...>...</at>) are one token.
+// The quote starts the quoted text only at the start of the token, eg: the apostrophe in don't is literal.
+// The backslash escapes the quote in the quoted text.
+func splitCommandText(text string) ([]commandToken, error) {
+	tokens := make([]commandToken, 0)
+	var token strings.Builder
+	inToken, quoted := false, false
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case unicode.IsSpace(c):
+			if inToken {
+				tokens = append(tokens, commandToken{text: token.String(), quoted: quoted})
+				token.Reset()
+				inToken, quoted = false, false
+			}
+		case !inToken && commandQuotes[c] != 0:
+			end := commandQuotes[c]
+			closed := false
+			for i++; i < len(runes); i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && (runes[i+1] == end || runes[i+1] == '\\') {
+					i++
+					token.WriteRune(runes[i])
+					continue
+				}
+				if runes[i] == end {
+					closed = true
+					break
+				}
+				token.WriteRune(runes[i])
+			}
+			if !closed {
+				return nil, fmt.Errorf("unclosed quote: %c", c)
+			}
+			inToken, quoted = true, true
+		case c == '<' && strings.HasPrefix(string(runes[i:]), "<at "):
+			end := strings.Index(string(runes[i:]), "</at>")
+			if end < 0 {
+				token.WriteRune(c)
+				inToken = true
+				continue
+			}
+			mention := string(runes[i:])[:end+len("</at>")]
+			token.WriteString(mention)
+			i += len([]rune(mention)) - 1
+			inToken = true
+		default:
+			token.WriteRune(c)
+			inToken = true
+		}
+	}
+	if inToken {
+		tokens = append(tokens, commandToken{text: token.String(), quoted: quoted})
+	}
+	return tokens, nil
+}
+
+// replyText reply the text to the chat, or to the user if the chat is empty
+func replyText(ctx context.Context, tenantKey, appID, chatID, openID, rootID, text string) error {
+	user, err := replyUser(chatID, openID)
+	if err != nil {
+		return err
+	}
+
+	_, err = message.SendTextMessage(ctx, tenantKey, appID, user, rootID, text)
+	return err
+}
+
+func replyUser(chatID, openID string) (*protocol.UserInfo, error) {
+	if chatID != "" {
+		return &protocol.UserInfo{ID: chatID, Type: protocol.UserTypeChatID}, nil
+	}
+	if openID != "" {
+		return &protocol.UserInfo{ID: openID, Type: protocol.UserTypeOpenID}, nil
+	}
+	return nil, fmt.Errorf("no chat or user to reply")
+}
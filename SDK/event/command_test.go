@@ -0,0 +1,149 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+func newTestRemindCommand() *event.Command {
+	return &event.Command{
+		Name: "remind",
+		Args: []*event.CommandArg{
+			{Name: "user", Type: event.ArgTypeMention, Required: true},
+			{Name: "text", Required: true},
+			{Name: "times", Type: event.ArgTypeInt, Default: "1"},
+		},
+		Flags: []*event.CommandFlag{
+			{Name: "after", Short: "a", Type: event.ArgTypeDuration, Default: "10m"},
+			{Name: "urgent", Short: "u", Type: event.ArgTypeBool},
+		},
+		Handler: func(ctx context.Context, msg *protocol.BotRecvMsg, args *event.CommandArgs) error {
+			return nil
+		},
+	}
+}
+
+func TestCommandParse(t *testing.T) {
+	cmd := newTestRemindCommand()
+
+	args, err := cmd.Parse(`<at open_id="ou_tom">@Tom Lee</at> "submit the report" 3 --after=1h -u`)
+	if err != nil {
+		t.Fatalf("parse: err[%v]", err)
+	}
+	if args.Mention("user") != "ou_tom" || args.String("text") != "submit the report" || args.Int("times") != 3 ||
+		args.Duration("after") != time.Hour || !args.Bool("urgent") {
+		t.Errorf("parse: unexpected args[%+v]", args)
+	}
+
+	// the defaults
+	args, err = cmd.Parse(`ou_tom “提交 报告”`)
+	if err != nil {
+		t.Fatalf("parse defaults: err[%v]", err)
+	}
+	if args.String("text") != "提交 报告" || args.Int("times") != 1 || args.Duration("after") != 10*time.Minute ||
+		args.Bool("urgent") || args.Has("after") {
+		t.Errorf("parse defaults: unexpected args[%+v]", args)
+	}
+
+	// the escaped quote, and -- ends the flags
+	args, err = cmd.Parse(`ou_tom -a 5m -- "say \"hi\""`)
+	if err != nil || args.String("text") != `say "hi"` || args.Duration("after") != 5*time.Minute {
+		t.Errorf("parse escape: args[%+v]err[%v]", args, err)
+	}
+
+	// the apostrophe in the word is literal
+	args, err = cmd.Parse(`ou_tom don't`)
+	if err != nil || args.String("text") != "don't" {
+		t.Errorf("parse apostrophe: args[%+v]err[%v]", args, err)
+	}
+
+	// the quoted token is literal, not a flag
+	args, err = cmd.Parse(`ou_tom "--urgent" -a "1h"`)
+	if err != nil || args.String("text") != "--urgent" || args.Bool("urgent") || args.Duration("after") != time.Hour {
+		t.Errorf("parse quoted flag: args[%+v]err[%v]", args, err)
+	}
+
+	cases := []struct {
+		text   string
+		reason string
+	}{
+		{``, "missing argument: <user>"},
+		{`tom hi`, "argument <user>"},
+		{`ou_tom hi abc`, "argument <times>"},
+		{`ou_tom hi 1 2`, "too many arguments"},
+		{`ou_tom hi --after`, "needs a value"},
+		{`ou_tom hi --after 1x`, "not a duration"},
+		{`ou_tom hi --unknown`, "unknown flag"},
+		{`ou_tom "hi`, "unclosed quote"},
+	}
+	for _, c := range cases {
+		_, err = cmd.Parse(c.text)
+		usageErr, ok := err.(*event.CommandUsageError)
+		if !ok || !strings.Contains(usageErr.Reason, c.reason) {
+			t.Errorf("text[%s]: err[%v]", c.text, err)
+		}
+	}
+}
+
+func TestCommandRegister(t *testing.T) {
+	appID := "cli_test_command"
+	router := event.NewRouter()
+
+	var called *event.CommandArgs
+	var usage *event.CommandUsageError
+	cmd := newTestRemindCommand()
+	cmd.Handler = func(ctx context.Context, msg *protocol.BotRecvMsg, args *event.CommandArgs) error {
+		called = args
+		return nil
+	}
+	cmd.UsageReply = func(ctx context.Context, msg *protocol.BotRecvMsg, err *event.CommandUsageError) error {
+		usage = err
+		return nil
+	}
+	if err := router.CommandRegister(appID, cmd); err != nil {
+		t.Fatalf("CommandRegister: err[%v]", err)
+	}
+
+	ctx := context.Background()
+	err := router.BotRecvMsgHandler(ctx, newTestTextMsg(appID, `Remind ou_tom hello -a 30s`))
+	if err != nil || called == nil || called.String("text") != "hello" || called.Duration("after") != 30*time.Second {
+		t.Errorf("command: called[%+v]err[%v]", called, err)
+	}
+
+	// the usage error is replied, the handler is not called
+	called = nil
+	err = router.BotRecvMsgHandler(ctx, newTestTextMsg(appID, `remind`))
+	if err != nil || called != nil || usage == nil {
+		t.Errorf("usage error: called[%+v]usage[%v]err[%v]", called, usage, err)
+	}
+	if usage != nil && !strings.Contains(usage.Error(), "usage: remind <user> <text> [times] [--after|-a duration] [--urgent|-u]") {
+		t.Errorf("usage: %s", usage.Error())
+	}
+}
+
+func TestCommandRegisterInvalid(t *testing.T) {
+	handler := func(ctx context.Context, msg *protocol.BotRecvMsg, args *event.CommandArgs) error { return nil }
+	cases := []*event.Command{
+		nil,
+		{Name: "nohandler"},
+		{Name: "order", Handler: handler, Args: []*event.CommandArg{{Name: "a"}, {Name: "b", Required: true}}},
+		{Name: "dup", Handler: handler, Args: []*event.CommandArg{{Name: "a"}}, Flags: []*event.CommandFlag{{Name: "a"}}},
+		{Name: "type", Handler: handler, Flags: []*event.CommandFlag{{Name: "n", Type: "float"}}},
+		{Name: "default", Handler: handler, Flags: []*event.CommandFlag{{Name: "n", Type: event.ArgTypeInt, Default: "x"}}},
+	}
+	router := event.NewRouter()
+	for i, cmd := range cases {
+		if err := router.CommandRegister("cli_test_command_invalid", cmd); err == nil {
+			t.Errorf("case[%d]: err is nil", i)
+		}
+	}
+}
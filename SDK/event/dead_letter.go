@@ -0,0 +1,373 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+const (
+	deadLetterKeyPrefix    = "event_dead_letter:"
+	deadLetterIndexKey     = "event_dead_letter_index"
+	deadLetterIndexLockKey = "event_dead_letter_index:lock"
+	deadLetterLockTTL      = 5 * time.Second
+	deadLetterLockRetry    = 100
+
+	redriveContextKey = "botframework_redrive"
+)
+
+// DeadLetter the event failed after the retries
+type DeadLetter struct {
+	ID         string                `json:"id"`
+	AppID      string                `json:"app_id"`
+	EventType  string                `json:"event_type"`
+	Header     *protocol.EventHeader `json:"header"`
+	Payload    json.RawMessage       `json:"payload"` // the event body passed to the handler
+	Error      string                `json:"error"`
+	Attempts   int                   `json:"attempts"`
+	CreateTime time.Time             `json:"create_time"`
+}
+
+// DeadLetterSink store the dead letters, eg: file, db, message queue
+type DeadLetterSink interface {
+	Put(ctx context.Context, letter *DeadLetter) error
+	List(ctx context.Context) ([]*DeadLetter, error)
+	Delete(ctx context.Context, id string) error
+}
+
+func newDeadLetter(header *protocol.EventHeader, eventBody []byte, err error, attempts int) *DeadLetter {
+	now := time.Now()
+	return &DeadLetter{
+		ID:         fmt.Sprintf("%s_%d", header.EventID, now.UnixNano()),
+		AppID:      header.AppID,
+		EventType:  header.EventType,
+		Header:     header,
+		Payload:    json.RawMessage(eventBody),
+		Error:      err.Error(),
+		Attempts:   attempts,
+		CreateTime: now,
+	}
+}
+
+// FileDeadLetterSink one dead letter per line(json lines)
+type FileDeadLetterSink struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileDeadLetterSink(path string) (*FileDeadLetterSink, error) {
+	if path == "" {
+		return nil, common.ErrEventDeadLetter.ErrorWithExtStr("file path is empty")
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0644)
+	if err != nil {
+		return nil, common.ErrEventDeadLetter.ErrorWithExtErr(err)
+	}
+	f.Close()
+
+	return &FileDeadLetterSink{path: path}, nil
+}
+
+func (s *FileDeadLetterSink) Put(ctx context.Context, letter *DeadLetter) error {
+	line, err := json.Marshal(letter)
+	if err != nil {
+		return common.ErrJsonMarshal.ErrorWithExtErr(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return common.ErrEventDeadLetter.ErrorWithExtErr(err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	if err != nil {
+		return common.ErrEventDeadLetter.ErrorWithExtErr(err)
+	}
+	return nil
+}
+
+func (s *FileDeadLetterSink) List(ctx context.Context) ([]*DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.read()
+}
+
+func (s *FileDeadLetterSink) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	letters, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for _, letter := range letters {
+		if letter.ID == id {
+			continue
+		}
+		line, err := json.Marshal(letter)
+		if err != nil {
+			return common.ErrJsonMarshal.ErrorWithExtErr(err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	// write a temp file and rename it, the file is not broken if the process exits while writing
+	tmp := s.path + ".tmp"
+	err = ioutil.WriteFile(tmp, buf.Bytes(), 0644)
+	if err != nil {
+		return common.ErrEventDeadLetter.ErrorWithExtErr(err)
+	}
+	err = os.Rename(tmp, s.path)
+	if err != nil {
+		return common.ErrEventDeadLetter.ErrorWithExtErr(err)
+	}
+	return nil
+}
+
+func (s *FileDeadLetterSink) read() ([]*DeadLetter, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, common.ErrEventDeadLetter.ErrorWithExtErr(err)
+	}
+	defer f.Close()
+
+	letters := make([]*DeadLetter, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10<<20)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		letter := &DeadLetter{}
+		err = json.Unmarshal(line, letter)
+		if err != nil {
+			return nil, common.ErrJsonUnmarshal.ErrorWithExtErr(err)
+		}
+		letters = append(letters, letter)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, common.ErrEventDeadLetter.ErrorWithExtErr(err)
+	}
+
+	return letters, nil
+}
+
+// DBDeadLetterSink store the dead letters by common.DBClient, the ids are kept in an index key.
+// The index is locked by common.TryLock while updating, so the instances sharing the db don't lose the ids.
+// The lock is atomic only if the client implements common.DBClientEx.
+type DBDeadLetterSink struct {
+	client     common.DBClient
+	expiration time.Duration
+	mu         sync.Mutex
+}
+
+// NewDBDeadLetterSink expiration 0 means the dead letters never expire
+func NewDBDeadLetterSink(client common.DBClient, expiration time.Duration) (*DBDeadLetterSink, error) {
+	if client == nil {
+		return nil, common.ErrEventDeadLetter.ErrorWithExtStr("db client is nil")
+	}
+
+	return &DBDeadLetterSink{
+		client:     client,
+		expiration: expiration,
+	}, nil
+}
+
+func (s *DBDeadLetterSink) Put(ctx context.Context, letter *DeadLetter) error {
+	value, err := json.Marshal(letter)
+	if err != nil {
+		return common.ErrJsonMarshal.ErrorWithExtErr(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err = s.client.Set(deadLetterKeyPrefix+letter.ID, string(value), s.expiration)
+	if err != nil {
+		return common.ErrEventDeadLetter.ErrorWithExtErr(err)
+	}
+
+	unlock, err := s.lockIndex()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	ids := s.index()
+	ids = append(ids, letter.ID)
+	return s.setIndex(ids)
+}
+
+func (s *DBDeadLetterSink) List(ctx context.Context) ([]*DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	letters := make([]*DeadLetter, 0)
+	for _, id := range s.index() {
+		value, err := s.client.Get(deadLetterKeyPrefix + id)
+		if err != nil || value == "" {
+			// expired or deleted
+			continue
+		}
+
+		letter := &DeadLetter{}
+		err = json.Unmarshal([]byte(value), letter)
+		if err != nil {
+			return nil, common.ErrJsonUnmarshal.ErrorWithExtErr(err)
+		}
+		letters = append(letters, letter)
+	}
+
+	return letters, nil
+}
+
+func (s *DBDeadLetterSink) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var err error
+	if client, ok := s.client.(common.DBClientEx); ok {
+		err = client.Del(deadLetterKeyPrefix + id)
+	} else {
+		err = s.client.Set(deadLetterKeyPrefix+id, "", time.Millisecond)
+	}
+	if err != nil {
+		return common.ErrEventDeadLetter.ErrorWithExtErr(err)
+	}
+
+	unlock, err := s.lockIndex()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	ids := s.index()
+	for i := range ids {
+		if ids[i] == id {
+			ids = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+	return s.setIndex(ids)
+}
+
+// lockIndex lock the index across the instances, return the unlock function
+func (s *DBDeadLetterSink) lockIndex() (func(), error) {
+	for i := 0; i < deadLetterLockRetry; i++ {
+		unlock, err := common.TryLock(s.client, deadLetterIndexLockKey, deadLetterLockTTL)
+		if err != nil {
+			return nil, common.ErrEventDeadLetter.ErrorWithExtErr(err)
+		}
+		if unlock != nil {
+			return unlock, nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil, common.ErrEventDeadLetter.ErrorWithExtStr("lock index timeout")
+}
+
+func (s *DBDeadLetterSink) index() []string {
+	ids := make([]string, 0)
+
+	value, err := s.client.Get(deadLetterIndexKey)
+	if err != nil || value == "" {
+		return ids
+	}
+	json.Unmarshal([]byte(value), &ids)
+	return ids
+}
+
+func (s *DBDeadLetterSink) setIndex(ids []string) error {
+	value, err := json.Marshal(ids)
+	if err != nil {
+		return common.ErrJsonMarshal.ErrorWithExtErr(err)
+	}
+
+	err = s.client.Set(deadLetterIndexKey, string(value), 0)
+	if err != nil {
+		return common.ErrEventDeadLetter.ErrorWithExtErr(err)
+	}
+	return nil
+}
+
+// RedriveDeadLetters dispatch the dead letters of the sink again by the default router, see Router.RedriveDeadLetters
+func RedriveDeadLetters(ctx context.Context, sink DeadLetterSink, filter func(letter *DeadLetter) bool) (int, error) {
+	return defaultRouter.RedriveDeadLetters(ctx, sink, filter)
+}
+
+// RedriveDeadLetters dispatch the dead letters synchronously through the normal path(rules, middleware, retry),
+// bypassing the async dispatch and the rate limit. The letter is deleted from the sink only after the handler succeeds,
+// the failed letter is kept in the sink instead of being dead-lettered again. filter nil means all letters.
+// Return the number of the letters handled successfully.
+func (r *Router) RedriveDeadLetters(ctx context.Context, sink DeadLetterSink, filter func(letter *DeadLetter) bool) (int, error) {
+	if sink == nil {
+		return 0, common.ErrEventDeadLetter.ErrorWithExtStr("sink is nil")
+	}
+
+	letters, err := sink.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx = context.WithValue(ctx, redriveContextKey, true)
+	count := 0
+	var errs []string
+	for _, letter := range letters {
+		if filter != nil && !filter(letter) {
+			continue
+		}
+		if letter.Header == nil {
+			errs = append(errs, fmt.Sprintf("id[%s] without header", letter.ID))
+			continue
+		}
+
+		err = r.eventCallbackHandler(ctx, letter.Header, letter.Payload)
+		if err != nil {
+			common.Logger(ctx).Errorf("redriveDeadLetter: id[%s]appid[%s]eventType[%s]error[%v]", letter.ID, letter.AppID, letter.EventType, err)
+			errs = append(errs, fmt.Sprintf("id[%s] error[%v]", letter.ID, err))
+			continue
+		}
+
+		err = sink.Delete(ctx, letter.ID)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("id[%s] delete error[%v]", letter.ID, err))
+			continue
+		}
+		count++
+	}
+
+	if len(errs) != 0 {
+		return count, common.ErrEventDeadLetter.ErrorWithExtStr(fmt.Sprintf("%v", errs))
+	}
+	return count, nil
+}
+
+// isRedrive the event is dispatched by RedriveDeadLetters
+func isRedrive(ctx context.Context) bool {
+	redrive, _ := ctx.Value(redriveContextKey).(bool)
+	return redrive
+}
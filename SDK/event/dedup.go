@@ -0,0 +1,135 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+)
+
+const (
+	DefaultDedupRetention = 12 * time.Hour
+
+	dedupKeyPrefix = "event_dedup:"
+	dedupValue     = "1"
+)
+
+// DedupManager the open platform retries event pushes, DedupManager drops the duplicate deliveries
+type DedupManager struct {
+	client    common.DBClient
+	retention time.Duration
+	dupCount  int64
+}
+
+var (
+	eventDedup *DedupManager
+	dedupRWMu  sync.RWMutex
+)
+
+func getEventDedup() *DedupManager {
+	dedupRWMu.RLock()
+	defer dedupRWMu.RUnlock()
+
+	return eventDedup
+}
+
+// EnableEventDedup demo:
+// event.EnableEventDedup(common.NewMemoryDBClient(), event.DefaultDedupRetention)
+//
+// Use a shared DBClient(eg: common.DefaultRedisClient) when running multiple instances.
+func EnableEventDedup(client common.DBClient, retention time.Duration) error {
+	if client == nil {
+		return common.ErrEventDedupParams.ErrorWithExtStr("db client is nil")
+	}
+	if retention <= 0 {
+		retention = DefaultDedupRetention
+	}
+
+	dedupRWMu.Lock()
+	defer dedupRWMu.Unlock()
+
+	eventDedup = &DedupManager{
+		client:    client,
+		retention: retention,
+	}
+	return nil
+}
+
+func DisableEventDedup() {
+	dedupRWMu.Lock()
+	defer dedupRWMu.Unlock()
+
+	eventDedup = nil
+}
+
+// GetDuplicateEventCount the number of duplicate events acknowledged without running handlers
+func GetDuplicateEventCount() int64 {
+	d := getEventDedup()
+	if d == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&d.dupCount)
+}
+
+// claim return false if the event has been received.
+// Errors of the db client are ignored, the event will be handled as usual.
+func (d *DedupManager) claim(ctx context.Context, appID, eventID string) bool {
+	if d == nil || eventID == "" {
+		return true
+	}
+
+	key := dedupKey(appID, eventID)
+
+	ok, err := common.SetIfAbsent(d.client, key, dedupValue, d.retention)
+	if err != nil {
+		common.Logger(ctx).Warnf("eventDedup: dbClientError[%v]appid[%s]eventID[%s]", err, appID, eventID)
+		return true
+	}
+
+	if !ok {
+		atomic.AddInt64(&d.dupCount, 1)
+		common.Logger(ctx).Infof("eventDedup: duplicate event, appid[%s]eventID[%s]", appID, eventID)
+	}
+	return ok
+}
+
+// release the event failed to be handled, let the next retry of the open platform go through
+func (d *DedupManager) release(ctx context.Context, appID, eventID string) {
+	if d == nil || eventID == "" {
+		return
+	}
+
+	key := dedupKey(appID, eventID)
+
+	var err error
+	if client, isEx := d.client.(common.DBClientEx); isEx {
+		err = client.Del(key)
+	} else {
+		err = d.client.Set(key, "", time.Millisecond)
+	}
+	if err != nil {
+		common.Logger(ctx).Warnf("eventDedup: releaseError[%v]appid[%s]eventID[%s]", err, appID, eventID)
+	}
+}
+
+func dedupKey(appID, eventID string) string {
+	return fmt.Sprintf("%s%s:%s", dedupKeyPrefix, appID, eventID)
+}
+
+// getEventID uuid is unique for each event, use ts+md5(content) if uuid is empty
+func getEventID(uuid, ts, content string) string {
+	if uuid != "" {
+		return uuid
+	}
+	if ts == "" {
+		return ""
+	}
+	return ts + "_" + common.GetMd5ByBytes([]byte(content))
+}
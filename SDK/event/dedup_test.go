@@ -0,0 +1,66 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+func TestEventDedup(t *testing.T) {
+	appID := "cli_test_event_dedup"
+	initTestApp(appID)
+
+	err := event.EnableEventDedup(common.NewMemoryDBClient(), time.Minute)
+	if err != nil {
+		t.Fatalf("EnableEventDedup failed: err[%v]", err)
+	}
+	defer event.DisableEventDedup()
+
+	ctx := context.Background()
+	called := 0
+	fail := true
+	event.EventRegister(appID, protocol.EventTypeAddBot, func(ctx context.Context, eventBody []byte) error {
+		called++
+		if fail {
+			return fmt.Errorf("handler failed")
+		}
+		return nil
+	})
+
+	body := newTestEventBody(appID, "dedup_uuid", protocol.EventTypeAddBot)
+
+	// failed delivery does not block the retry
+	_, err = event.EventCallback(ctx, body, appID)
+	if err == nil {
+		t.Errorf("EventDedup: handler error should be returned")
+	}
+
+	fail = false
+	for i := 0; i < 3; i++ {
+		_, err = event.EventCallback(ctx, body, appID)
+		if err != nil {
+			t.Errorf("EventDedup: delivery[%d] err[%v]", i, err)
+		}
+	}
+	if called != 2 {
+		t.Errorf("EventDedup: handler called[%d], want 2", called)
+	}
+	if event.GetDuplicateEventCount() != 2 {
+		t.Errorf("EventDedup: duplicate count[%d], want 2", event.GetDuplicateEventCount())
+	}
+
+	// another event
+	_, err = event.EventCallback(ctx, newTestEventBody(appID, "dedup_uuid_2", protocol.EventTypeAddBot), appID)
+	if err != nil || called != 3 {
+		t.Errorf("EventDedup: new event called[%d]err[%v]", called, err)
+	}
+}
@@ -0,0 +1,406 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+const (
+	DefaultDialogTimeout = 10 * time.Minute
+	DialogEnd            = "$end" // returned by DialogStep.Next to complete the dialog
+	dialogKeyPrefix      = "dialog:"
+)
+
+var DefaultDialogCancelWords = []string{"cancel", "取消"}
+
+// DialogStep one question of the dialog, the answer is saved in the slot named by Name
+type DialogStep struct {
+	Name   string
+	Prompt string // sent when the step starts
+
+	// check the answer, the error is replied with the prompt and the step is asked again
+	Validate func(ctx context.Context, answer string, session *DialogSession) error
+	// branch by the answers: return the name of the next step, DialogEnd to complete the dialog,
+	// or "" for the next one in Dialog.Steps. nil means the next one in Dialog.Steps
+	Next func(ctx context.Context, session *DialogSession) string
+}
+
+// Dialog the multi-turn conversation with a user, demo:
+//
+// router.DialogRegister(appID, &event.Dialog{
+// 	Name: "create_incident",
+// 	Steps: []*event.DialogStep{
+// 		{Name: "title", Prompt: "What's the title?"},
+// 		{Name: "severity", Prompt: "What's the severity? P0/P1/P2", Validate: event.DialogChoices("P0", "P1", "P2")},
+// 	},
+// 	OnComplete: func(ctx context.Context, msg *protocol.BotRecvMsg, session *event.DialogSession) error {
+// 		// session.Slots["title"], session.Slots["severity"]
+// 		return nil
+// 	},
+// })
+// router.BotRecvMsgRegister(appID, "incident", func(ctx context.Context, msg *protocol.BotRecvMsg) error {
+// 	return router.StartDialog(ctx, msg, "create_incident")
+// })
+//
+// While the dialog is active, the messages of the user in the chat are sent to the dialog instead of the commands.
+type Dialog struct {
+	Name        string
+	Steps       []*DialogStep
+	Timeout     time.Duration // the dialog is dropped if the user doesn't answer in time, 0 means DefaultDialogTimeout
+	CancelWords []string      // the answers canceling the dialog, case insensitive. nil means DefaultDialogCancelWords
+	CancelText  string        // replied when the dialog is canceled, empty means no reply
+
+	OnComplete func(ctx context.Context, msg *protocol.BotRecvMsg, session *DialogSession) error
+	OnCancel   func(ctx context.Context, msg *protocol.BotRecvMsg, session *DialogSession) error
+
+	// reply the prompts and the validation errors, nil means sending the text to the chat
+	Reply func(ctx context.Context, msg *protocol.BotRecvMsg, text string) error
+}
+
+// DialogSession the progress of the dialog, stored in common.DBClient
+type DialogSession struct {
+	Dialog    string            `json:"dialog"`
+	Step      string            `json:"step"`
+	Slots     map[string]string `json:"slots"`
+	StartTime int64             `json:"start_time"`
+}
+
+// DialogChoices the answer must be one of the choices, case insensitive
+func DialogChoices(choices ...string) func(ctx context.Context, answer string, session *DialogSession) error {
+	return func(ctx context.Context, answer string, session *DialogSession) error {
+		for _, choice := range choices {
+			if strings.EqualFold(choice, answer) {
+				return nil
+			}
+		}
+		return fmt.Errorf("please choose one of: %s", strings.Join(choices, ", "))
+	}
+}
+
+// DialogManager the dialogs of the apps and the sessions
+type DialogManager struct {
+	store   common.DBClient
+	dialogs map[string]map[string]*Dialog
+	rwMu    sync.RWMutex
+}
+
+func newDialogManager() *DialogManager {
+	return &DialogManager{
+		store:   common.NewMemoryDBClient(),
+		dialogs: make(map[string]map[string]*Dialog, 0),
+	}
+}
+
+func (m *DialogManager) getDialog(appID, name string) *Dialog {
+	m.rwMu.RLock()
+	defer m.rwMu.RUnlock()
+
+	return m.dialogs[appID][name]
+}
+
+func (m *DialogManager) hasDialog(appID string) bool {
+	m.rwMu.RLock()
+	defer m.rwMu.RUnlock()
+
+	return len(m.dialogs[appID]) != 0
+}
+
+func (m *DialogManager) getStore() common.DBClient {
+	m.rwMu.RLock()
+	defer m.rwMu.RUnlock()
+
+	return m.store
+}
+
+// DialogRegister register the dialog, the dialog with the same name is replaced
+func DialogRegister(appID string, dialog *Dialog) error {
+	return defaultRouter.DialogRegister(appID, dialog)
+}
+
+// SetDialogStore store the sessions in the db client, eg: redis for multiple replicas. The default is in memory.
+func SetDialogStore(client common.DBClient) error {
+	return defaultRouter.SetDialogStore(client)
+}
+
+// StartDialog start the dialog with the sender of the message, the active dialog of the sender is replaced
+func StartDialog(ctx context.Context, msg *protocol.BotRecvMsg, name string) error {
+	return defaultRouter.StartDialog(ctx, msg, name)
+}
+
+// CancelDialog drop the active dialog of the sender of the message without calling OnCancel
+func CancelDialog(ctx context.Context, msg *protocol.BotRecvMsg) error {
+	return defaultRouter.CancelDialog(ctx, msg)
+}
+
+// GetDialogSession return nil if the sender of the message has no active dialog
+func GetDialogSession(ctx context.Context, msg *protocol.BotRecvMsg) (*DialogSession, error) {
+	return defaultRouter.GetDialogSession(ctx, msg)
+}
+
+// DialogRegister register the dialog, the dialog with the same name is replaced
+func (r *Router) DialogRegister(appID string, dialog *Dialog) error {
+	if appID == "" || dialog == nil || dialog.Name == "" {
+		return common.ErrBotRecvMsgDialog.ErrorWithExtStr(
+			fmt.Sprintf("params is empty or nil. AppID[%s]DialogIsNil[%t]", appID, dialog == nil))
+	}
+	if len(dialog.Steps) == 0 {
+		return common.ErrBotRecvMsgDialog.ErrorWithExtStr(fmt.Sprintf("dialog[%s] has no step", dialog.Name))
+	}
+	names := make(map[string]bool, len(dialog.Steps))
+	for _, step := range dialog.Steps {
+		if step == nil || step.Name == "" || names[step.Name] {
+			return common.ErrBotRecvMsgDialog.ErrorWithExtStr(fmt.Sprintf("dialog[%s] step name is empty or duplicate", dialog.Name))
+		}
+		names[step.Name] = true
+	}
+
+	m := r.dialogManager
+	m.rwMu.Lock()
+	defer m.rwMu.Unlock()
+
+	if _, ok := m.dialogs[appID]; !ok {
+		m.dialogs[appID] = make(map[string]*Dialog, 0)
+	}
+	m.dialogs[appID][dialog.Name] = dialog
+	return nil
+}
+
+// SetDialogStore store the sessions in the db client, eg: redis for multiple replicas. The default is in memory.
+func (r *Router) SetDialogStore(client common.DBClient) error {
+	if client == nil {
+		return common.ErrBotRecvMsgDialog.ErrorWithExtStr("db client is nil")
+	}
+
+	r.dialogManager.rwMu.Lock()
+	defer r.dialogManager.rwMu.Unlock()
+
+	r.dialogManager.store = client
+	return nil
+}
+
+// StartDialog start the dialog with the sender of the message, the active dialog of the sender is replaced
+func (r *Router) StartDialog(ctx context.Context, msg *protocol.BotRecvMsg, name string) error {
+	dialog := r.dialogManager.getDialog(msg.AppID, name)
+	if dialog == nil {
+		return common.ErrBotRecvMsgDialog.ErrorWithExtStr(fmt.Sprintf("appid[%s]dialog[%s] has not been registered", msg.AppID, name))
+	}
+
+	session := &DialogSession{
+		Dialog:    name,
+		Step:      dialog.Steps[0].Name,
+		Slots:     make(map[string]string),
+		StartTime: time.Now().Unix(),
+	}
+	err := r.saveDialogSession(msg, dialog, session)
+	if err != nil {
+		return err
+	}
+
+	return dialog.reply(ctx, msg, dialog.Steps[0].Prompt)
+}
+
+// CancelDialog drop the active dialog of the sender of the message without calling OnCancel
+func (r *Router) CancelDialog(ctx context.Context, msg *protocol.BotRecvMsg) error {
+	return r.deleteDialogSession(msg)
+}
+
+// GetDialogSession return nil if the sender of the message has no active dialog
+func (r *Router) GetDialogSession(ctx context.Context, msg *protocol.BotRecvMsg) (*DialogSession, error) {
+	value, err := r.dialogManager.getStore().Get(dialogSessionKey(msg))
+	if err != nil || value == "" {
+		// not found or expired
+		return nil, nil
+	}
+
+	session := &DialogSession{}
+	err = json.Unmarshal([]byte(value), session)
+	if err != nil {
+		return nil, common.ErrJsonUnmarshal.ErrorWithExtErr(err)
+	}
+	if session.Slots == nil {
+		session.Slots = make(map[string]string)
+	}
+	return session, nil
+}
+
+// dialogHandler return the handler of the active dialog of the sender, nil means no active dialog
+func (r *Router) dialogHandler(ctx context.Context, msg *protocol.BotRecvMsg) (string, HandlerBotMsg) {
+	if !r.dialogManager.hasDialog(msg.AppID) {
+		return "", nil
+	}
+
+	session, err := r.GetDialogSession(ctx, msg)
+	if err != nil || session == nil {
+		if err != nil {
+			common.Logger(ctx).Warnf("dialog: getSessionError[%v]appid[%s]openid[%s]", err, msg.AppID, msg.OpenID)
+		}
+		return "", nil
+	}
+
+	dialog := r.dialogManager.getDialog(msg.AppID, session.Dialog)
+	if dialog == nil || dialog.step(session.Step) == nil {
+		// the dialog has been changed, drop the session
+		common.Logger(ctx).Warnf("dialog: sessionInvalid, appid[%s]dialog[%s]step[%s]", msg.AppID, session.Dialog, session.Step)
+		r.deleteDialogSession(msg)
+		return "", nil
+	}
+
+	return dialog.Name, func(ctx context.Context, msg *protocol.BotRecvMsg) error {
+		return r.answerDialog(ctx, msg, dialog, session)
+	}
+}
+
+func (r *Router) answerDialog(ctx context.Context, msg *protocol.BotRecvMsg, dialog *Dialog, session *DialogSession) error {
+	answer := strings.TrimSpace(msg.TextParam)
+
+	cancelWords := dialog.CancelWords
+	if cancelWords == nil {
+		cancelWords = DefaultDialogCancelWords
+	}
+	for _, word := range cancelWords {
+		if strings.EqualFold(word, answer) {
+			err := r.deleteDialogSession(msg)
+			if err != nil {
+				return err
+			}
+			if dialog.OnCancel != nil {
+				return dialog.OnCancel(ctx, msg, session)
+			}
+			return dialog.reply(ctx, msg, dialog.CancelText)
+		}
+	}
+
+	step := dialog.step(session.Step)
+	if answer == "" {
+		// eg: the image, or the post without text. Ask again, and refresh the timeout
+		if err := r.saveDialogSession(msg, dialog, session); err != nil {
+			return err
+		}
+		return dialog.reply(ctx, msg, step.Prompt)
+	}
+	if step.Validate != nil {
+		if err := step.Validate(ctx, answer, session); err != nil {
+			// ask again, and refresh the timeout
+			if err := r.saveDialogSession(msg, dialog, session); err != nil {
+				return err
+			}
+			return dialog.reply(ctx, msg, strings.TrimSpace(err.Error()+"\n"+step.Prompt))
+		}
+	}
+	session.Slots[step.Name] = answer
+
+	next, err := dialog.next(ctx, step, session)
+	if err != nil {
+		// the dialog can't go on, drop the session instead of completing it without the slots
+		r.deleteDialogSession(msg)
+		return err
+	}
+	if next == nil {
+		err := r.deleteDialogSession(msg)
+		if err != nil {
+			return err
+		}
+		if dialog.OnComplete != nil {
+			return dialog.OnComplete(ctx, msg, session)
+		}
+		return nil
+	}
+
+	session.Step = next.Name
+	err = r.saveDialogSession(msg, dialog, session)
+	if err != nil {
+		return err
+	}
+	return dialog.reply(ctx, msg, next.Prompt)
+}
+
+func (r *Router) saveDialogSession(msg *protocol.BotRecvMsg, dialog *Dialog, session *DialogSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return common.ErrJsonMarshal.ErrorWithExtErr(err)
+	}
+
+	timeout := dialog.Timeout
+	if timeout <= 0 {
+		timeout = DefaultDialogTimeout
+	}
+	err = r.dialogManager.getStore().Set(dialogSessionKey(msg), string(data), timeout)
+	if err != nil {
+		return common.ErrBotRecvMsgDialog.ErrorWithExtErr(err)
+	}
+	return nil
+}
+
+func (r *Router) deleteDialogSession(msg *protocol.BotRecvMsg) error {
+	store := r.dialogManager.getStore()
+
+	var err error
+	if client, ok := store.(common.DBClientEx); ok {
+		err = client.Del(dialogSessionKey(msg))
+	} else {
+		err = store.Set(dialogSessionKey(msg), "", time.Millisecond)
+	}
+	if err != nil {
+		return common.ErrBotRecvMsgDialog.ErrorWithExtErr(err)
+	}
+	return nil
+}
+
+func dialogSessionKey(msg *protocol.BotRecvMsg) string {
+	return dialogKeyPrefix + msg.AppID + ":" + msg.TenantKey + ":" + msg.OpenChatID + ":" + msg.OpenID
+}
+
+func (d *Dialog) step(name string) *DialogStep {
+	for _, step := range d.Steps {
+		if step.Name == name {
+			return step
+		}
+	}
+	return nil
+}
+
+// next return nil if the dialog is completed, error if DialogStep.Next returns the unknown step
+func (d *Dialog) next(ctx context.Context, step *DialogStep, session *DialogSession) (*DialogStep, error) {
+	if step.Next != nil {
+		switch name := step.Next(ctx, session); name {
+		case "":
+		case DialogEnd:
+			return nil, nil
+		default:
+			next := d.step(name)
+			if next == nil {
+				return nil, common.ErrBotRecvMsgDialog.ErrorWithExtStr(
+					fmt.Sprintf("dialog[%s]step[%s] next step[%s] not found", d.Name, step.Name, name))
+			}
+			return next, nil
+		}
+	}
+
+	for i, s := range d.Steps {
+		if s == step && i+1 < len(d.Steps) {
+			return d.Steps[i+1], nil
+		}
+	}
+	return nil, nil
+}
+
+func (d *Dialog) reply(ctx context.Context, msg *protocol.BotRecvMsg, text string) error {
+	if text == "" {
+		return nil
+	}
+	if d.Reply != nil {
+		return d.Reply(ctx, msg, text)
+	}
+	return replyText(ctx, msg.TenantKey, msg.AppID, msg.OpenChatID, msg.OpenID, msg.OpenMessageID, text)
+}
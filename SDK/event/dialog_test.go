@@ -0,0 +1,191 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+func newTestIncidentRouter(appID string, replies *[]string, completed *map[string]string) *event.Router {
+	router := event.NewRouter()
+	router.DialogRegister(appID, &event.Dialog{
+		Name: "create_incident",
+		Steps: []*event.DialogStep{
+			{Name: "title", Prompt: "title?"},
+			{
+				Name:     "severity",
+				Prompt:   "severity?",
+				Validate: event.DialogChoices("P0", "P1", "P2"),
+				Next: func(ctx context.Context, session *event.DialogSession) string {
+					if session.Slots["severity"] == "P0" {
+						return "oncall"
+					}
+					return event.DialogEnd
+				},
+			},
+			{Name: "oncall", Prompt: "oncall?"},
+		},
+		Timeout:    50 * time.Millisecond,
+		CancelText: "canceled",
+		OnComplete: func(ctx context.Context, msg *protocol.BotRecvMsg, session *event.DialogSession) error {
+			*completed = session.Slots
+			return nil
+		},
+		Reply: func(ctx context.Context, msg *protocol.BotRecvMsg, text string) error {
+			*replies = append(*replies, text)
+			return nil
+		},
+	})
+	router.BotRecvMsgRegister(appID, "incident", func(ctx context.Context, msg *protocol.BotRecvMsg) error {
+		return router.StartDialog(ctx, msg, "create_incident")
+	})
+	router.BotRecvMsgRegister(appID, protocol.CmdDefault, func(ctx context.Context, msg *protocol.BotRecvMsg) error {
+		*replies = append(*replies, "default:"+msg.TextParam)
+		return nil
+	})
+	return router
+}
+
+func TestDialog(t *testing.T) {
+	appID := "cli_test_dialog"
+	var replies []string
+	var completed map[string]string
+	router := newTestIncidentRouter(appID, &replies, &completed)
+
+	ctx := context.Background()
+	for _, text := range []string{"incident", "db is down", "P3", "P0", "ou_tom"} {
+		if err := router.BotRecvMsgHandler(ctx, newTestMsg(appID, testFields{"open_id": "ou_1", "text_without_at_bot": text})); err != nil {
+			t.Errorf("text[%s]: err[%v]", text, err)
+		}
+	}
+
+	expected := []string{"title?", "severity?", "please choose one of: P0, P1, P2\nseverity?", "oncall?"}
+	if strings.Join(replies, "|") != strings.Join(expected, "|") {
+		t.Errorf("replies: %q", replies)
+	}
+	if completed["title"] != "db is down" || completed["severity"] != "P0" || completed["oncall"] != "ou_tom" {
+		t.Errorf("completed: %v", completed)
+	}
+
+	// the dialog is completed, the message is dispatched to the commands
+	replies = nil
+	router.BotRecvMsgHandler(ctx, newTestMsg(appID, testFields{"open_id": "ou_1", "text_without_at_bot": "hello"}))
+	if strings.Join(replies, "|") != "default:hello" {
+		t.Errorf("after completed: %q", replies)
+	}
+}
+
+func TestDialogCancelAndTimeout(t *testing.T) {
+	appID := "cli_test_dialog_cancel"
+	var replies []string
+	var completed map[string]string
+	router := newTestIncidentRouter(appID, &replies, &completed)
+
+	ctx := context.Background()
+	router.BotRecvMsgHandler(ctx, newTestMsg(appID, testFields{"open_id": "ou_1", "text_without_at_bot": "incident"}))
+
+	// the other user is not in the dialog
+	router.BotRecvMsgHandler(ctx, newTestMsg(appID, testFields{"open_id": "ou_2", "text_without_at_bot": "db is down"}))
+
+	router.BotRecvMsgHandler(ctx, newTestMsg(appID, testFields{"open_id": "ou_1", "text_without_at_bot": "Cancel"}))
+	expected := []string{"title?", "default:db is down", "canceled"}
+	if strings.Join(replies, "|") != strings.Join(expected, "|") {
+		t.Errorf("cancel: %q", replies)
+	}
+	msg := &protocol.BotRecvMsg{AppID: appID, TenantKey: "tenant", OpenChatID: "oc_1", OpenID: "ou_1"}
+	if session, _ := router.GetDialogSession(ctx, msg); session != nil {
+		t.Errorf("cancel: session[%+v]", session)
+	}
+
+	// the dialog times out
+	replies = nil
+	router.BotRecvMsgHandler(ctx, newTestMsg(appID, testFields{"open_id": "ou_1", "text_without_at_bot": "incident"}))
+	if session, _ := router.GetDialogSession(ctx, msg); session == nil || session.Step != "title" {
+		t.Errorf("start: session[%+v]", session)
+	}
+	time.Sleep(80 * time.Millisecond)
+	router.BotRecvMsgHandler(ctx, newTestMsg(appID, testFields{"open_id": "ou_1", "text_without_at_bot": "db is down"}))
+	expected = []string{"title?", "default:db is down"}
+	if strings.Join(replies, "|") != strings.Join(expected, "|") || completed != nil {
+		t.Errorf("timeout: %q", replies)
+	}
+}
+
+func TestDialogStore(t *testing.T) {
+	appID := "cli_test_dialog_store"
+	var replies []string
+	var completed map[string]string
+	router := newTestIncidentRouter(appID, &replies, &completed)
+
+	// the session is shared by the routers with the same store, eg: the replicas
+	db := &common.MemoryDBClient{}
+	db.InitDB(nil)
+	router.SetDialogStore(db)
+	other := newTestIncidentRouter(appID, &replies, &completed)
+	other.SetDialogStore(db)
+
+	ctx := context.Background()
+	router.BotRecvMsgHandler(ctx, newTestMsg(appID, testFields{"open_id": "ou_1", "text_without_at_bot": "incident"}))
+	other.BotRecvMsgHandler(ctx, newTestMsg(appID, testFields{"open_id": "ou_1", "text_without_at_bot": "db is down"}))
+	router.BotRecvMsgHandler(ctx, newTestMsg(appID, testFields{"open_id": "ou_1", "text_without_at_bot": "p1"}))
+	if completed["title"] != "db is down" || completed["severity"] != "p1" {
+		t.Errorf("completed: %v, replies: %q", completed, replies)
+	}
+
+	if err := router.StartDialog(ctx, &protocol.BotRecvMsg{AppID: appID}, "unknown"); err == nil {
+		t.Errorf("start unknown dialog: err is nil")
+	}
+	if err := router.DialogRegister(appID, &event.Dialog{Name: "empty"}); err == nil {
+		t.Errorf("register dialog without step: err is nil")
+	}
+}
+
+func TestDialogEmptyAnswerAndUnknownStep(t *testing.T) {
+	appID := "cli_test_dialog_empty_answer"
+	var replies []string
+	var completed map[string]string
+	router := newTestIncidentRouter(appID, &replies, &completed)
+	router.DialogRegister(appID, &event.Dialog{
+		Name:  "broken",
+		Steps: []*event.DialogStep{{Name: "title", Prompt: "title?", Next: func(ctx context.Context, session *event.DialogSession) string { return "unknown" }}},
+		OnComplete: func(ctx context.Context, msg *protocol.BotRecvMsg, session *event.DialogSession) error {
+			completed = session.Slots
+			return nil
+		},
+		Reply: func(ctx context.Context, msg *protocol.BotRecvMsg, text string) error {
+			replies = append(replies, text)
+			return nil
+		},
+	})
+
+	// the image has no text, the step is asked again
+	ctx := context.Background()
+	router.BotRecvMsgHandler(ctx, newTestMsg(appID, testFields{"open_id": "ou_1", "text_without_at_bot": "incident"}))
+	router.BotRecvMsgHandler(ctx, newTestMsg(appID, testFields{"open_id": "ou_1", "msg_type": "image", "image_key": "img_1"}))
+	msg := &protocol.BotRecvMsg{AppID: appID, TenantKey: "tenant", OpenChatID: "oc_1", OpenID: "ou_1"}
+	session, _ := router.GetDialogSession(ctx, msg)
+	if strings.Join(replies, "|") != "title?|title?" || session == nil || len(session.Slots) != 0 {
+		t.Errorf("image: replies[%q]session[%+v]", replies, session)
+	}
+
+	// the unknown next step drops the dialog without completing it
+	if err := router.StartDialog(ctx, msg, "broken"); err != nil {
+		t.Fatalf("StartDialog: err[%v]", err)
+	}
+	err := router.BotRecvMsgHandler(ctx, newTestMsg(appID, testFields{"open_id": "ou_1", "text_without_at_bot": "db is down"}))
+	if err == nil || completed != nil {
+		t.Errorf("unknown step: completed[%v]err[%v]", completed, err)
+	}
+	if session, _ := router.GetDialogSession(ctx, msg); session != nil {
+		t.Errorf("unknown step: session[%+v]", session)
+	}
+}
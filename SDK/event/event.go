@@ -14,6 +14,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/bitly/go-simplejson"
 	"github.com/larksuite/botframework-go/SDK/appconfig"
@@ -22,62 +23,254 @@ import (
 	"github.com/larksuite/botframework-go/SDK/protocol"
 )
 
+const (
+	eventHeaderContextKey = "botframework_event_header"
+)
+
 type EventHandler func(ctx context.Context, eventBody []byte) error
 
 type EventHandlerManager struct {
-	mapHandler map[string]map[string]EventHandler //[app_id][eventType][function]
+	mapHandler         map[string]map[string][]EventHandler //[app_id][eventType][functions]
+	mapFallback        map[string]EventHandler              //[app_id][function], for unregistered event type
+	mapPolicy          map[string]FanOutPolicy              //[app_id][policy]
+	ignoreUnregistered map[string]bool
+	rwMu               sync.RWMutex
+}
+
+func newEventHandlerManager() *EventHandlerManager {
+	return &EventHandlerManager{
+		mapHandler:         make(map[string]map[string][]EventHandler, 0),
+		mapFallback:        make(map[string]EventHandler, 0),
+		mapPolicy:          make(map[string]FanOutPolicy, 0),
+		ignoreUnregistered: make(map[string]bool, 0),
+	}
 }
 
+// Set replace the handlers of the event types
 func (a *EventHandlerManager) Set(appID, eventTypeList string, eventHandler EventHandler) {
 	if eventHandler == nil {
 		return
 	}
 
+	a.rwMu.Lock()
+	defer a.rwMu.Unlock()
+
 	if m, ok := a.mapHandler[appID]; !ok || m == nil {
-		a.mapHandler[appID] = make(map[string]EventHandler, 0)
+		a.mapHandler[appID] = make(map[string][]EventHandler, 0)
 	}
 
-	s := strings.Split(strings.Trim(eventTypeList, " "), ",")
-	for _, eventType := range s {
-		a.mapHandler[appID][eventType] = eventHandler
+	for _, eventType := range splitEventTypeList(eventTypeList) {
+		a.mapHandler[appID][eventType] = []EventHandler{eventHandler}
 	}
 }
 
+// Add append a handler to the event types
+func (a *EventHandlerManager) Add(appID, eventTypeList string, eventHandler EventHandler) {
+	if eventHandler == nil {
+		return
+	}
+
+	a.rwMu.Lock()
+	defer a.rwMu.Unlock()
+
+	if m, ok := a.mapHandler[appID]; !ok || m == nil {
+		a.mapHandler[appID] = make(map[string][]EventHandler, 0)
+	}
+
+	for _, eventType := range splitEventTypeList(eventTypeList) {
+		a.mapHandler[appID][eventType] = append(a.mapHandler[appID][eventType], eventHandler)
+	}
+}
+
+// Delete remove the handlers of the event types, return false if none of them has been registered
+func (a *EventHandlerManager) Delete(appID, eventTypeList string) bool {
+	a.rwMu.Lock()
+	defer a.rwMu.Unlock()
+
+	deleted := false
+	for _, eventType := range splitEventTypeList(eventTypeList) {
+		if _, ok := a.mapHandler[appID][eventType]; ok {
+			delete(a.mapHandler[appID], eventType)
+			deleted = true
+		}
+	}
+	return deleted
+}
+
+// Replace replace the handlers of the event types, return false without any change if one of them has not been registered
+func (a *EventHandlerManager) Replace(appID, eventTypeList string, eventHandler EventHandler) bool {
+	if eventHandler == nil {
+		return false
+	}
+
+	a.rwMu.Lock()
+	defer a.rwMu.Unlock()
+
+	eventTypes := splitEventTypeList(eventTypeList)
+	for _, eventType := range eventTypes {
+		if _, ok := a.mapHandler[appID][eventType]; !ok {
+			return false
+		}
+	}
+	for _, eventType := range eventTypes {
+		a.mapHandler[appID][eventType] = []EventHandler{eventHandler}
+	}
+	return true
+}
+
+func (a *EventHandlerManager) SetFallback(appID string, eventHandler EventHandler) {
+	a.rwMu.Lock()
+	defer a.rwMu.Unlock()
+
+	if eventHandler == nil {
+		delete(a.mapFallback, appID)
+		return
+	}
+
+	a.mapFallback[appID] = eventHandler
+}
+
+func (a *EventHandlerManager) SetPolicy(appID string, policy FanOutPolicy) {
+	a.rwMu.Lock()
+	defer a.rwMu.Unlock()
+
+	a.mapPolicy[appID] = policy
+}
+
+func (a *EventHandlerManager) SetIgnoreUnregistered(appID string, ignore bool) {
+	a.rwMu.Lock()
+	defer a.rwMu.Unlock()
+
+	a.ignoreUnregistered[appID] = ignore
+}
+
+func (a *EventHandlerManager) IsIgnoreUnregistered(appID string) bool {
+	a.rwMu.RLock()
+	defer a.rwMu.RUnlock()
+
+	return a.ignoreUnregistered[appID]
+}
+
+// Get return the handler of the event type, multiple handlers are combined by the fan-out policy of the app.
+// The fallback handler of the app is returned if the event type is unregistered.
 func (a *EventHandlerManager) Get(appID string, eventType string) (EventHandler, error) {
+	a.rwMu.RLock()
+	defer a.rwMu.RUnlock()
+
+	handlers, ok := a.mapHandler[appID][eventType]
+	if !ok {
+		if fallback, ok := a.mapFallback[appID]; ok {
+			return fallback, nil
+		}
+	}
+
 	if _, ok := a.mapHandler[appID]; !ok {
 		return nil, common.ErrEventAppIDUnregistered.ErrorWithExtStr(fmt.Sprintf("appid[%s]eventType[%s]", appID, eventType))
 	}
-	if _, ok := a.mapHandler[appID][eventType]; !ok {
+	if !ok {
 		return nil, common.ErrEventTypeUnregistered.ErrorWithExtStr(fmt.Sprintf("appid[%s]eventType[%s]", appID, eventType))
 	}
-	if a.mapHandler[appID][eventType] == nil {
+	if len(handlers) == 0 {
 		return nil, common.ErrEventHandlerIsNil.ErrorWithExtStr(fmt.Sprintf("appid[%s]eventType[%s]", appID, eventType))
 	}
+	if len(handlers) == 1 {
+		return handlers[0], nil
+	}
+
+	return fanOut(a.mapPolicy[appID], handlers), nil
+}
 
-	return a.mapHandler[appID][eventType], nil
+// EventRegister the handler replaces the handlers registered before
+func EventRegister(appID, eventTypeList string, eventHandler EventHandler) error {
+	return defaultRouter.EventRegister(appID, eventTypeList, eventHandler)
 }
 
-var eventManager *EventHandlerManager
+// EventUnregister remove the handlers of the event types
+func EventUnregister(appID, eventTypeList string) error {
+	return defaultRouter.EventUnregister(appID, eventTypeList)
+}
 
-func init() {
-	eventManager = &EventHandlerManager{
-		mapHandler: make(map[string]map[string]EventHandler, 0),
-	}
+// EventReplace hot swap the handlers of the registered event types
+func EventReplace(appID, eventTypeList string, eventHandler EventHandler) error {
+	return defaultRouter.EventReplace(appID, eventTypeList, eventHandler)
 }
 
-func EventRegister(appID, eventTypeList string, eventHandler EventHandler) error {
+func EventCallback(ctx context.Context, body string, appID string) (string, error) {
+	return defaultRouter.EventCallbackWithHeader(ctx, body, appID, nil)
+}
+
+// EventCallbackWithHeader header is used to verify signature, see SetEventSignatureCheck. demo:
+// header := map[string]string{
+// 	"X-Lark-Request-Timestamp": c.Request.Header.Get("X-Lark-Request-Timestamp"),
+// 	"X-Lark-Request-Nonce":     c.Request.Header.Get("X-Lark-Request-Nonce"),
+// 	"X-Lark-Signature":         c.Request.Header.Get("X-Lark-Signature"),
+// }
+func EventCallbackWithHeader(ctx context.Context, body string, appID string, header map[string]string) (string, error) {
+	return defaultRouter.EventCallbackWithHeader(ctx, body, appID, header)
+}
+
+// EventRegister the handler replaces the handlers registered before
+func (r *Router) EventRegister(appID, eventTypeList string, eventHandler EventHandler) error {
 	// 参数校验
 	if appID == "" || eventTypeList == "" || eventHandler == nil {
 		return common.ErrEventTypeRegister.ErrorWithExtStr(
 			fmt.Sprintf("params is empty or nil. AppID[%s]EventType[%s]HandlerIsNil[%t]", appID, eventTypeList, eventHandler == nil))
 	}
 
-	eventManager.Set(appID, eventTypeList, eventHandler)
+	r.eventManager.Set(appID, eventTypeList, eventHandler)
 
 	return nil
 }
 
-func EventCallback(ctx context.Context, body string, appID string) (string, error) {
+// EventUnregister remove the handlers of the event types
+func (r *Router) EventUnregister(appID, eventTypeList string) error {
+	if appID == "" || eventTypeList == "" {
+		return common.ErrEventTypeRegister.ErrorWithExtStr(
+			fmt.Sprintf("params is empty. AppID[%s]EventType[%s]", appID, eventTypeList))
+	}
+
+	if !r.eventManager.Delete(appID, eventTypeList) {
+		return common.ErrEventTypeUnregistered.ErrorWithExtStr(fmt.Sprintf("appid[%s]eventType[%s]", appID, eventTypeList))
+	}
+
+	return nil
+}
+
+// EventReplace hot swap the handlers of the registered event types, the handlers appended by EventSubscribe are replaced too.
+// Return error without any change if one of the event types has not been registered.
+func (r *Router) EventReplace(appID, eventTypeList string, eventHandler EventHandler) error {
+	if appID == "" || eventTypeList == "" || eventHandler == nil {
+		return common.ErrEventTypeRegister.ErrorWithExtStr(
+			fmt.Sprintf("params is empty or nil. AppID[%s]EventType[%s]HandlerIsNil[%t]", appID, eventTypeList, eventHandler == nil))
+	}
+
+	if !r.eventManager.Replace(appID, eventTypeList, eventHandler) {
+		return common.ErrEventTypeUnregistered.ErrorWithExtStr(fmt.Sprintf("appid[%s]eventType[%s]", appID, eventTypeList))
+	}
+
+	return nil
+}
+
+func (r *Router) EventCallback(ctx context.Context, body string, appID string) (string, error) {
+	return r.EventCallbackWithHeader(ctx, body, appID, nil)
+}
+
+// EventCallbackWithHeader the same as the package function EventCallbackWithHeader, but dispatch the event by the handlers of the router
+func (r *Router) EventCallbackWithHeader(ctx context.Context, body string, appID string, header map[string]string) (string, error) {
+	if !r.drainer.Acquire() {
+		return "", common.ErrEventShuttingDown.ErrorWithExtStr(fmt.Sprintf("appid[%s]", appID))
+	}
+	defer r.drainer.Release()
+
+	record := newRecord(RecordKindEvent, appID, header, body)
+
+	challenge, err := r.eventCallback(ctx, body, appID, header, record)
+	record.finish(ctx, err)
+
+	return challenge, err
+}
+
+func (r *Router) eventCallback(ctx context.Context, body string, appID string, header map[string]string, record *Record) (string, error) {
 	// check params
 	if body == "" || appID == "" {
 		return "", common.ErrEventParams.Error()
@@ -89,6 +282,14 @@ func EventCallback(ctx context.Context, body string, appID string) (string, erro
 		return "", common.ErrAppConfNotFound.ErrorWithExtErr(err)
 	}
 
+	// check signature, the signature of the redacted record can't be verified
+	if option := eventSignature.Get(appID); option != nil && !isRedactedReplay(ctx) {
+		err = verifyEventSignature(ctx, option, appConf.EncryptKey, header, body)
+		if err != nil {
+			return "", err
+		}
+	}
+
 	// decrypt data
 	var content string
 	if appConf.EncryptKey != "" {
@@ -107,13 +308,20 @@ func EventCallback(ctx context.Context, body string, appID string) (string, erro
 	}
 
 	// check token
-	if callbackBase.Token != appConf.VerifyToken {
+	token := callbackBase.GetToken()
+	if token != appConf.VerifyToken {
 		return "", common.ErrEventVeriToken.ErrorWithExtStr(
-			fmt.Sprintf("eventVTokenSize[%d]confVTokenSize[%d]", len(callbackBase.Token), len(appConf.VerifyToken)))
+			fmt.Sprintf("eventVTokenSize[%d]confVTokenSize[%d]", len(token), len(appConf.VerifyToken)))
+	}
+
+	// schema 2.0 has no type, it is always event callback
+	callbackType := callbackBase.Type
+	if callbackBase.IsSchemaV2() {
+		callbackType = protocol.EventCallback
 	}
 
 	// dispatch event type
-	switch callbackBase.Type {
+	switch callbackType {
 	case protocol.EventChallenge:
 		if appConf.AppType == protocol.ISVApp {
 			auth.ReSendAppTicket(ctx, appConf.AppID, appConf.AppSecret)
@@ -121,8 +329,31 @@ func EventCallback(ctx context.Context, body string, appID string) (string, erro
 
 		return callbackBase.Challenge, nil
 	case protocol.EventCallback:
-		err = eventCallbackHandler(ctx, appID, content)
+		header, eventBody, err := parseEvent(&callbackBase, content)
+		if err != nil {
+			return "", err
+		}
+		if record != nil {
+			record.EventType = header.EventType
+		}
+
+		// check params
+		if header.AppID != appID {
+			return "", common.ErrEventAppIDNotMatch.Error()
+		}
+
+		// drop the duplicate delivery, the replayed event is dispatched again
+		replayed := IsReplay(ctx)
+		dedup := getEventDedup()
+		if !replayed && !dedup.claim(ctx, appID, header.EventID) {
+			return "", nil
+		}
+
+		err = r.eventCallbackHandler(ctx, header, eventBody)
 		if err != nil {
+			if !replayed {
+				dedup.release(ctx, appID, header.EventID)
+			}
 			return "", err
 		}
 	default:
@@ -132,49 +363,133 @@ func EventCallback(ctx context.Context, body string, appID string) (string, erro
 	return "", nil
 }
 
-func eventCallbackHandler(ctx context.Context, appID, content string) error {
-	// get type and app_id
+// parseEvent get the normalized header and the event body of schema 1.0 and 2.0
+func parseEvent(callbackBase *protocol.CallbackBase, content string) (*protocol.EventHeader, []byte, error) {
 	jsonBody, err := simplejson.NewJson([]byte(content))
 	if err != nil {
-		return common.ErrJsonUnmarshal.ErrorWithExtErr(err)
+		return nil, nil, common.ErrJsonUnmarshal.ErrorWithExtErr(err)
 	}
 	jsonEvent := jsonBody.Get("event")
 	if jsonEvent.Interface() == nil {
-		return common.ErrEventGetJsonEvent.Error()
+		return nil, nil, common.ErrEventGetJsonEvent.Error()
 	}
-	eventType, err := jsonEvent.Get("type").String()
-	if err != nil {
-		return common.ErrEventGetJsonType.ErrorWithExtErr(err)
+
+	var header *protocol.EventHeader
+	if callbackBase.IsSchemaV2() {
+		header = &protocol.EventHeader{
+			Schema:     protocol.EventSchemaV2,
+			EventID:    callbackBase.Header.EventID,
+			EventType:  callbackBase.Header.EventType,
+			AppID:      callbackBase.Header.AppID,
+			TenantKey:  callbackBase.Header.TenantKey,
+			CreateTime: callbackBase.Header.CreateTime,
+		}
+		if header.EventType == "" {
+			return nil, nil, common.ErrEventGetJsonType.ErrorWithExtStr("header.event_type is empty")
+		}
+		if header.AppID == "" {
+			return nil, nil, common.ErrEventGetJsonAppID.ErrorWithExtStr("header.app_id is empty")
+		}
+	} else {
+		eventType, err := jsonEvent.Get("type").String()
+		if err != nil {
+			return nil, nil, common.ErrEventGetJsonType.ErrorWithExtErr(err)
+		}
+		eventAppID, err := jsonEvent.Get("app_id").String()
+		if err != nil {
+			return nil, nil, common.ErrEventGetJsonAppID.ErrorWithExtErr(err)
+		}
+		tenantKey, _ := jsonEvent.Get("tenant_key").String()
+
+		header = &protocol.EventHeader{
+			Schema:     protocol.EventSchemaV1,
+			EventID:    getEventID(callbackBase.Uuid, callbackBase.Ts, content),
+			EventType:  eventType,
+			AppID:      eventAppID,
+			TenantKey:  tenantKey,
+			CreateTime: callbackBase.Ts,
+		}
 	}
-	eventAppID, err := jsonEvent.Get("app_id").String()
+
+	byteEvent, err := jsonEvent.MarshalJSON()
 	if err != nil {
-		return common.ErrEventGetJsonAppID.ErrorWithExtErr(err)
+		return nil, nil, common.ErrJsonMarshal.ErrorWithExtErr(err)
 	}
 
-	// check params
-	if eventAppID != appID {
-		return common.ErrEventAppIDNotMatch.Error()
+	return header, byteEvent, nil
+}
+
+func (r *Router) eventCallbackHandler(ctx context.Context, header *protocol.EventHeader, eventBody []byte) error {
+	appID := header.AppID
+
+	meta := newEventMeta(header, eventBody)
+	ctx = context.WithValue(ctx, eventHeaderContextKey, header)
+	ctx = withEventMeta(ctx, meta)
+
+	// routing rules
+	var handler EventHandler
+	var err error
+	if rule := r.ruleManager.Match(ctx, appID, meta); rule != nil {
+		if rule.Handler == nil {
+			common.Logger(ctx).Infof("eventCallback: drop event by rule[%s]", rule.Name)
+			return nil
+		}
+		handler = rule.Handler
+	} else {
+		// dispatch event type
+		handler, err = r.eventManager.Get(appID, header.EventType)
+		if err != nil {
+			if r.eventManager.IsIgnoreUnregistered(appID) {
+				common.Logger(ctx).Infof("eventCallback: ignore unregistered event, err[%v]", err)
+				return nil
+			}
+			return err
+		}
 	}
 
-	// dispatch event type
-	handler, err := eventManager.Get(appID, eventType)
-	if err != nil {
-		return err
+	handler = handlerTimeout.WrapEvent(appID, header.EventType, handler)
+	handler = middlewareManager.WrapEvent(appID, handler)
+	handler = eventRetry.Wrap(appID, header, handler)
+
+	// the limits are checked before the dispatch, the event exceeding the limit is not queued
+	dispatch := func(ctx context.Context) {
+		if err := r.dispatchEvent(ctx, header, handler, eventBody); err != nil {
+			common.Logger(ctx).Errorf("rateLimit: delayed event error[%v]", err)
+		}
+	}
+	if !isRedrive(ctx) && !rateLimiter.Allow(ctx, appID, meta, r.drainer, dispatch) {
+		return nil
 	}
 
-	byteEvent, err := jsonEvent.MarshalJSON()
-	if err != nil {
-		return common.ErrJsonMarshal.ErrorWithExtErr(err)
+	return r.dispatchEvent(ctx, header, handler, eventBody)
+}
+
+func (r *Router) dispatchEvent(ctx context.Context, header *protocol.EventHeader, handler EventHandler, eventBody []byte) error {
+	// async mode: ack the open platform immediately. The redrive waits for the result
+	if d := getAsyncDispatcher(); d != nil && !isRedrive(ctx) {
+		return d.enqueue(ctx, header.AppID, header.EventType, handler, eventBody)
 	}
 
-	err = handler(ctx, byteEvent)
+	err := handler(ctx, eventBody)
 	if err != nil {
+		if isTimeout(err) {
+			return err
+		}
 		return common.ErrEventHandlerFailed.ErrorWithExtErr(err)
 	}
 
 	return nil
 }
 
+// EventHeaderFromContext get the normalized event header in the event handler
+func EventHeaderFromContext(ctx context.Context) *protocol.EventHeader {
+	header, ok := ctx.Value(eventHeaderContextKey).(*protocol.EventHeader)
+	if !ok {
+		return nil
+	}
+	return header
+}
+
 func eventDataDecrypter(encryptData, keyStr string) (string, error) {
 	type AESMsg struct {
 		Encrypt string `json:"encrypt"`
@@ -222,3 +537,11 @@ func eventDataDecrypter(encryptData, keyStr string) (string, error) {
 
 	return string(buf[n : m+1]), nil
 }
+
+func splitEventTypeList(eventTypeList string) []string {
+	s := strings.Split(strings.Trim(eventTypeList, " "), ",")
+	for i := range s {
+		s[i] = strings.Trim(s[i], " ")
+	}
+	return s
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/larksuite/botframework-go/SDK/appconfig"
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+const (
+	testVerifyToken = "event_verify_token"
+)
+
+// testFields override the fields of the test event or message
+type testFields map[string]interface{}
+
+// initTestApp each test uses its own appid, the handlers registered by other tests do not interfere
+func initTestApp(appID string) {
+	appconfig.Init(appconfig.AppConfig{
+		AppID:       appID,
+		AppType:     protocol.InternalApp,
+		VerifyToken: testVerifyToken,
+	})
+}
+
+// newTestEventBody the schema 1.0 event callback, the fields override the fields of the event
+func newTestEventBody(appID, uuid, eventType string, fields ...testFields) string {
+	evt := testFields{
+		"type":         eventType,
+		"app_id":       appID,
+		"tenant_key":   "tenant",
+		"open_chat_id": "oc_1",
+		"open_id":      "ou_1",
+	}
+	for _, f := range fields {
+		for k, v := range f {
+			evt[k] = v
+		}
+	}
+
+	data, _ := json.Marshal(testFields{
+		"uuid":  uuid,
+		"ts":    "1577808000.000",
+		"token": testVerifyToken,
+		"type":  "event_callback",
+		"event": evt,
+	})
+	return string(data)
+}
+
+// newTestMsg the message event of the bot, the fields override the text message of ou_sender in oc_1
+func newTestMsg(appID string, fields testFields) []byte {
+	msg := testFields{
+		"type":         "message",
+		"app_id":       appID,
+		"msg_type":     "text",
+		"tenant_key":   "tenant",
+		"open_chat_id": "oc_1",
+		"open_id":      "ou_sender",
+	}
+	for k, v := range fields {
+		msg[k] = v
+	}
+
+	data, _ := json.Marshal(msg)
+	return data
+}
+
+func newTestTextMsg(appID, text string) []byte {
+	return newTestMsg(appID, testFields{"text_without_at_bot": text})
+}
+
+// encryptTestEvent the same as the encryption of the open platform: base64(iv + AES-256-CBC(sha256(key), PKCS7(content)))
+func encryptTestEvent(content, encryptKey string) string {
+	key := sha256.Sum256([]byte(encryptKey))
+	block, _ := aes.NewCipher(key[:])
+
+	padding := aes.BlockSize - len(content)%aes.BlockSize
+	plain := append([]byte(content), bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	buf := make([]byte, aes.BlockSize+len(plain))
+	iv := buf[:aes.BlockSize]
+	copy(iv, "0123456789abcdef")
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(buf[aes.BlockSize:], plain)
+
+	return fmt.Sprintf(`{"encrypt":"%s"}`, base64.StdEncoding.EncodeToString(buf))
+}
+
+func TestEventCallback(t *testing.T) {
+	appID := "cli_test_event_callback"
+	initTestApp(appID)
+
+	ctx := context.Background()
+	called := 0
+	event.EventRegister(appID, protocol.EventTypeAddBot, func(ctx context.Context, eventBody []byte) error {
+		called++
+		return nil
+	})
+
+	// challenge
+	challenge, err := event.EventCallback(ctx, fmt.Sprintf(`{"challenge":"abc","token":"%s","type":"url_verification"}`, testVerifyToken), appID)
+	if err != nil || challenge != "abc" {
+		t.Errorf("EventCallback challenge failed: challenge[%s]err[%v]", challenge, err)
+	}
+
+	// event
+	_, err = event.EventCallback(ctx, newTestEventBody(appID, "uuid_1", protocol.EventTypeAddBot), appID)
+	if err != nil || called != 1 {
+		t.Errorf("EventCallback event failed: called[%d]err[%v]", called, err)
+	}
+
+	// invalid token
+	_, err = event.EventCallback(ctx, `{"token":"invalid","type":"event_callback"}`, appID)
+	if err == nil {
+		t.Errorf("EventCallback invalid token: should fail")
+	}
+
+	// unregistered event type
+	_, err = event.EventCallback(ctx, newTestEventBody(appID, "uuid_2", protocol.EventTypeRemoveBot), appID)
+	if err == nil {
+		t.Errorf("EventCallback unregistered type: should fail")
+	}
+}
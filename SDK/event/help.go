@@ -0,0 +1,241 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/message"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+// CommandInfo the metadata of the command, registered by BotRecvMsgRegisterWithInfo or Command.Info
+type CommandInfo struct {
+	Name            string // set by the registration
+	Description     string
+	I18NDescription map[protocol.Language]string // the localized description, Description is used if the language is missing
+	Usage           string                       // eg: remind <user> <text> [--after duration]
+	Examples        []string
+	Aliases         []string // the aliases are dispatched to the same handler
+	Hidden          bool     // not listed by the help command, but "help <cmd>" shows it
+}
+
+// LocalDescription the description in the language
+func (info *CommandInfo) LocalDescription(language protocol.Language) string {
+	if desc, ok := info.I18NDescription[language]; ok && desc != "" {
+		return desc
+	}
+	return info.Description
+}
+
+// HelpOption the option of the built-in help command
+type HelpOption struct {
+	// the languages of the reply, the client shows the one of the user's language. nil means ZhCN and EnUS
+	Languages []protocol.Language
+	Card      bool // reply a card, false means a rich text message
+}
+
+var helpTexts = map[protocol.Language]map[string]string{
+	protocol.ZhCN: {
+		"title":       "命令列表",
+		"detail":      "命令：%s",
+		"description": "说明",
+		"usage":       "用法",
+		"examples":    "示例",
+		"aliases":     "别名",
+		"footer":      "发送 \"help <命令>\" 查看命令详情",
+		"unknown":     "未找到命令：%s",
+		"help":        "查看命令列表和命令详情",
+	},
+	protocol.EnUS: {
+		"title":       "Commands",
+		"detail":      "Command: %s",
+		"description": "Description",
+		"usage":       "Usage",
+		"examples":    "Examples",
+		"aliases":     "Aliases",
+		"footer":      "Send \"help <command>\" for the details",
+		"unknown":     "Unknown command: %s",
+		"help":        "Show the commands and the details of a command",
+	},
+	protocol.JaJP: {
+		"title":       "コマンド一覧",
+		"detail":      "コマンド：%s",
+		"description": "説明",
+		"usage":       "使い方",
+		"examples":    "例",
+		"aliases":     "別名",
+		"footer":      "\"help <コマンド>\" を送信すると詳細を表示します",
+		"unknown":     "コマンドが見つかりません：%s",
+		"help":        "コマンド一覧とコマンドの詳細を表示します",
+	},
+}
+
+func helpText(language protocol.Language, key string) string {
+	texts, ok := helpTexts[language]
+	if !ok {
+		texts = helpTexts[protocol.EnUS]
+	}
+	return texts[key]
+}
+
+// HelpCommandRegister register the built-in help command:
+// "help" lists the commands which are not hidden, "help <cmd>" shows the details of the command.
+func HelpCommandRegister(appID string, option *HelpOption) error {
+	return defaultRouter.HelpCommandRegister(appID, option)
+}
+
+// HelpCommandRegister register the built-in help command:
+// "help" lists the commands which are not hidden, "help <cmd>" shows the details of the command.
+func (r *Router) HelpCommandRegister(appID string, option *HelpOption) error {
+	opt := HelpOption{}
+	if option != nil {
+		opt = *option
+	}
+	if len(opt.Languages) == 0 {
+		opt.Languages = []protocol.Language{protocol.ZhCN, protocol.EnUS}
+	}
+
+	info := &CommandInfo{
+		Description: helpText(protocol.EnUS, "help"),
+		I18NDescription: map[protocol.Language]string{
+			protocol.ZhCN: helpText(protocol.ZhCN, "help"),
+			protocol.JaJP: helpText(protocol.JaJP, "help"),
+		},
+		Usage:    protocol.CmdHelp + " [command]",
+		Examples: []string{protocol.CmdHelp, protocol.CmdHelp + " " + protocol.CmdHelp},
+	}
+
+	return r.BotRecvMsgRegisterWithInfo(appID, protocol.CmdHelp, func(ctx context.Context, msg *protocol.BotRecvMsg) error {
+		cmdName := strings.Fields(msg.TextParam)
+		name := ""
+		if len(cmdName) > 0 {
+			name = cmdName[0]
+		}
+
+		user, err := replyUser(msg.OpenChatID, msg.OpenID)
+		if err != nil {
+			return err
+		}
+
+		if opt.Card {
+			card, err := r.HelpCard(msg.AppID, name, opt.Languages)
+			if err != nil {
+				return err
+			}
+			_, err = message.SendCardMessage(ctx, msg.TenantKey, msg.AppID, user, msg.OpenMessageID, *card, false)
+			return err
+		}
+
+		post := r.HelpRichText(msg.AppID, name, opt.Languages)
+		_, err = message.SendRichTextMessage(ctx, msg.TenantKey, msg.AppID, user, msg.OpenMessageID, post)
+		return err
+	}, info)
+}
+
+// helpSection the block of the help content
+type helpSection struct {
+	title string
+	lines []string
+}
+
+// helpContent "" lists the commands, or the details of the command
+func (r *Router) helpContent(appID, cmdName string, language protocol.Language) (string, []helpSection) {
+	if cmdName == "" {
+		lines := make([]string, 0)
+		for _, info := range r.cmdHandler.GetInfos(appID) {
+			if info.Hidden || info.Name == protocol.CmdDefault {
+				continue
+			}
+			line := info.Name
+			if desc := info.LocalDescription(language); desc != "" {
+				line += " - " + desc
+			}
+			lines = append(lines, line)
+		}
+		return helpText(language, "title"), []helpSection{{lines: lines}, {lines: []string{helpText(language, "footer")}}}
+	}
+
+	info := r.cmdHandler.GetInfo(appID, cmdName)
+	if info == nil {
+		if _, err := r.cmdHandler.Get(appID, cmdName); err != nil {
+			return helpText(language, "title"), []helpSection{
+				{lines: []string{fmt.Sprintf(helpText(language, "unknown"), cmdName)}},
+				{lines: []string{helpText(language, "footer")}},
+			}
+		}
+		info = &CommandInfo{Name: strings.ToLower(cmdName)}
+	}
+
+	sections := make([]helpSection, 0)
+	if desc := info.LocalDescription(language); desc != "" {
+		sections = append(sections, helpSection{title: helpText(language, "description"), lines: []string{desc}})
+	}
+	if info.Usage != "" {
+		sections = append(sections, helpSection{title: helpText(language, "usage"), lines: []string{info.Usage}})
+	}
+	if len(info.Examples) != 0 {
+		sections = append(sections, helpSection{title: helpText(language, "examples"), lines: info.Examples})
+	}
+	if len(info.Aliases) != 0 {
+		sections = append(sections, helpSection{title: helpText(language, "aliases"), lines: []string{strings.Join(info.Aliases, ", ")}})
+	}
+	return fmt.Sprintf(helpText(language, "detail"), info.Name), sections
+}
+
+// HelpRichText the rich text of the help command, cmdName "" lists the commands
+func (r *Router) HelpRichText(appID, cmdName string, languages []protocol.Language) map[protocol.Language]*protocol.RichTextForm {
+	post := make(map[protocol.Language]*protocol.RichTextForm, len(languages))
+	for _, language := range languages {
+		title, sections := r.helpContent(appID, cmdName, language)
+
+		content := message.NewRichTextContent()
+		for _, section := range sections {
+			if section.title != "" {
+				content.AddElementBlock(message.NewTextTag(section.title+":", false, 1))
+			}
+			for _, line := range section.lines {
+				content.AddElementBlock(message.NewTextTag(line, false, 1))
+			}
+		}
+		post[language] = message.NewRichTextForm(&title, content)
+	}
+	return post
+}
+
+// HelpCard the card of the help command, cmdName "" lists the commands
+func (r *Router) HelpCard(appID, cmdName string, languages []protocol.Language) (*protocol.CardForm, error) {
+	builder := &message.CardBuilder{}
+	i18nTitle := protocol.I18NForm{}
+
+	for _, language := range languages {
+		title, sections := r.helpContent(appID, cmdName, language)
+		i18nTitle[language.String()] = title
+
+		builder.SwitchLocale(language)
+		for _, section := range sections {
+			text := strings.Join(section.lines, "\n")
+			if section.title != "" {
+				text = "**" + section.title + "**\n" + text
+			}
+			builder.AddDIVBlock(message.NewMDText(text, nil, nil, nil), nil, nil)
+		}
+	}
+
+	title := ""
+	if len(languages) != 0 {
+		title = i18nTitle[languages[0].String()]
+	}
+	builder.AddHeader(*message.NewPlainText(&title, &i18nTitle, nil), "")
+
+	card, err := builder.BuildForm()
+	if err != nil {
+		return nil, common.ErrCardParams.ErrorWithExtErr(err)
+	}
+	return card, nil
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+func richTextString(form *protocol.RichTextForm) string {
+	lines := []string{form.Title}
+	for _, elements := range *form.Content {
+		for _, element := range elements {
+			lines = append(lines, *element.Text)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestHelpCommand(t *testing.T) {
+	appID := "cli_test_help"
+	router := event.NewRouter()
+	handler := func(ctx context.Context, msg *protocol.BotRecvMsg) error { return nil }
+
+	router.BotRecvMsgRegister(appID, protocol.CmdDefault, handler)
+	router.BotRecvMsgRegisterWithInfo(appID, "show", handler, &event.CommandInfo{
+		Description:     "show the report",
+		I18NDescription: map[protocol.Language]string{protocol.ZhCN: "查看报表"},
+		Usage:           "show <name>",
+		Examples:        []string{"show weekly"},
+		Aliases:         []string{"ls"},
+	})
+	router.BotRecvMsgRegisterWithInfo(appID, "debug", handler, &event.CommandInfo{Description: "debug", Hidden: true})
+	router.BotRecvMsgRegister(appID, "ping", handler)
+	router.CommandRegister(appID, &event.Command{
+		Name:  "remind",
+		Args:  []*event.CommandArg{{Name: "text", Required: true}},
+		Flags: []*event.CommandFlag{{Name: "after", Type: event.ArgTypeDuration}},
+		Info:  &event.CommandInfo{Description: "remind me"},
+		Handler: func(ctx context.Context, msg *protocol.BotRecvMsg, args *event.CommandArgs) error {
+			return nil
+		},
+	})
+	if err := router.HelpCommandRegister(appID, nil); err != nil {
+		t.Fatalf("HelpCommandRegister: err[%v]", err)
+	}
+
+	// the list
+	post := router.HelpRichText(appID, "", []protocol.Language{protocol.ZhCN, protocol.EnUS})
+	en := richTextString(post[protocol.EnUS])
+	expected := "Commands\nhelp - Show the commands and the details of a command\nping\nremind - remind me\nshow - show the report\nSend \"help <command>\" for the details"
+	if en != expected {
+		t.Errorf("list en:\n%s", en)
+	}
+	if zh := richTextString(post[protocol.ZhCN]); !strings.Contains(zh, "show - 查看报表") || strings.Contains(zh, "debug") {
+		t.Errorf("list zh:\n%s", zh)
+	}
+
+	// the details, by the alias
+	en = richTextString(router.HelpRichText(appID, "LS", []protocol.Language{protocol.EnUS})[protocol.EnUS])
+	expected = "Command: show\nDescription:\nshow the report\nUsage:\nshow <name>\nExamples:\nshow weekly\nAliases:\nls"
+	if en != expected {
+		t.Errorf("detail en:\n%s", en)
+	}
+
+	// the usage of the command registered by CommandRegister
+	en = richTextString(router.HelpRichText(appID, "remind", []protocol.Language{protocol.EnUS})[protocol.EnUS])
+	if !strings.Contains(en, "remind <text> [--after duration]") {
+		t.Errorf("detail of remind:\n%s", en)
+	}
+
+	// the hidden command can be shown by name
+	en = richTextString(router.HelpRichText(appID, "debug", []protocol.Language{protocol.EnUS})[protocol.EnUS])
+	if !strings.HasPrefix(en, "Command: debug") {
+		t.Errorf("detail of debug:\n%s", en)
+	}
+
+	en = richTextString(router.HelpRichText(appID, "deploy", []protocol.Language{protocol.EnUS})[protocol.EnUS])
+	if !strings.Contains(en, "Unknown command: deploy") {
+		t.Errorf("unknown command:\n%s", en)
+	}
+
+	// the card
+	card, err := router.HelpCard(appID, "", []protocol.Language{protocol.ZhCN, protocol.EnUS})
+	if err != nil {
+		t.Fatalf("HelpCard: err[%v]", err)
+	}
+	data, _ := json.Marshal(card)
+	if !strings.Contains(string(data), `"zh_cn":"命令列表"`) || len(card.I18NElements["en_us"]) != 2 {
+		t.Errorf("card: %s", data)
+	}
+}
+
+func TestCommandAlias(t *testing.T) {
+	appID := "cli_test_command_alias"
+	router := event.NewRouter()
+
+	called := 0
+	router.BotRecvMsgRegisterWithInfo(appID, "show", func(ctx context.Context, msg *protocol.BotRecvMsg) error {
+		called++
+		return nil
+	}, &event.CommandInfo{Aliases: []string{"ls"}})
+
+	ctx := context.Background()
+	if err := router.BotRecvMsgHandler(ctx, newTestTextMsg(appID, "ls weekly")); err != nil || called != 1 {
+		t.Errorf("alias: called[%d]err[%v]", called, err)
+	}
+
+	// the aliases are removed with the command
+	router.BotRecvMsgUnregister(appID, "show")
+	if err := router.BotRecvMsgHandler(ctx, newTestTextMsg(appID, "ls weekly")); err == nil {
+		t.Errorf("alias after unregister: err is nil")
+	}
+	if infos := router.CommandInfos(appID); len(infos) != 0 {
+		t.Errorf("infos after unregister: %+v", infos)
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+// MessageMatcher dispatch the messages which are not a registered command.
+// The conditions are combined by AND, at least one condition is required.
+type MessageMatcher struct {
+	Name string // unique in the app, used to unregister the matcher and in the log
+
+	// match the text without @bot, the named groups are set to msg.MatchGroups, eg: (?P<ticket>[A-Z]+-\d+)
+	Regexp *regexp.Regexp
+	// match the beginning of the text without @bot, case insensitive. msg.TextParam is the text after the prefix
+	Prefix    string
+	Predicate func(ctx context.Context, msg *protocol.BotRecvMsg) bool
+
+	Priority int // the higher priority is matched first, the same priority in the order of registration
+	Handler  HandlerBotMsg
+}
+
+// match return the text param and the named groups if the message is matched
+func (m *MessageMatcher) match(ctx context.Context, msg *protocol.BotRecvMsg, text string) (bool, string, map[string]string) {
+	textParam := text
+	if m.Prefix != "" {
+		if len(text) < len(m.Prefix) || !strings.EqualFold(text[:len(m.Prefix)], m.Prefix) {
+			return false, "", nil
+		}
+		textParam = strings.TrimSpace(text[len(m.Prefix):])
+	}
+
+	var groups map[string]string
+	if m.Regexp != nil {
+		match := m.Regexp.FindStringSubmatch(text)
+		if match == nil {
+			return false, "", nil
+		}
+		groups = make(map[string]string)
+		for i, name := range m.Regexp.SubexpNames() {
+			if name != "" {
+				groups[name] = match[i]
+			}
+		}
+	}
+
+	if m.Predicate != nil && !m.Predicate(ctx, msg) {
+		return false, "", nil
+	}
+	return true, textParam, groups
+}
+
+// MessageMatcherManager the matchers of each app, sorted by the priority
+type MessageMatcherManager struct {
+	mapMatcher map[string][]*MessageMatcher //[app_id][matchers]
+	rwMu       sync.RWMutex
+}
+
+func newMessageMatcherManager() *MessageMatcherManager {
+	return &MessageMatcherManager{
+		mapMatcher: make(map[string][]*MessageMatcher, 0),
+	}
+}
+
+// Add insert the matcher by the priority, return false if the name has been registered in the app
+func (p *MessageMatcherManager) Add(appID string, matcher *MessageMatcher) bool {
+	p.rwMu.Lock()
+	defer p.rwMu.Unlock()
+
+	for _, m := range p.mapMatcher[appID] {
+		if m.Name == matcher.Name {
+			return false
+		}
+	}
+
+	// copy on write, the matchers being evaluated are not changed
+	matchers := append(append([]*MessageMatcher{}, p.mapMatcher[appID]...), matcher)
+	sort.SliceStable(matchers, func(i, j int) bool {
+		return matchers[i].Priority > matchers[j].Priority
+	})
+	p.mapMatcher[appID] = matchers
+	return true
+}
+
+// Delete remove the matcher, return false if the name has not been registered in the app
+func (p *MessageMatcherManager) Delete(appID, name string) bool {
+	p.rwMu.Lock()
+	defer p.rwMu.Unlock()
+
+	matchers := p.mapMatcher[appID]
+	for i := range matchers {
+		if matchers[i].Name == name {
+			p.mapMatcher[appID] = append(append([]*MessageMatcher{}, matchers[:i]...), matchers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Match return the first matcher matches the message, nil if none
+func (p *MessageMatcherManager) Match(ctx context.Context, msg *protocol.BotRecvMsg, text string) (*MessageMatcher, string, map[string]string) {
+	p.rwMu.RLock()
+	matchers := p.mapMatcher[msg.AppID]
+	p.rwMu.RUnlock()
+
+	for _, matcher := range matchers {
+		if ok, textParam, groups := matcher.match(ctx, msg, text); ok {
+			return matcher, textParam, groups
+		}
+	}
+	return nil, "", nil
+}
+
+// MessageMatcherRegister demo:
+// // JIRA-123 => show the ticket
+// event.MessageMatcherRegister(appID, &event.MessageMatcher{
+// 	Name:    "ticket",
+// 	Regexp:  regexp.MustCompile(`(?P<ticket>[A-Z]+-\d+)`),
+// 	Handler: BotRecvMsgTicket, // msg.MatchGroups["ticket"]
+// })
+// // "/deploy v1.2" => msg.TextParam is "v1.2"
+// event.MessageMatcherRegister(appID, &event.MessageMatcher{Name: "deploy", Prefix: "/deploy", Priority: 10, Handler: BotRecvMsgDeploy})
+//
+// The message is dispatched in the order: the active dialog, the command(the first word), the matchers by priority, the default command.
+func MessageMatcherRegister(appID string, matcher *MessageMatcher) error {
+	return defaultRouter.MessageMatcherRegister(appID, matcher)
+}
+
+// MessageMatcherUnregister remove the matcher by name
+func MessageMatcherUnregister(appID, name string) error {
+	return defaultRouter.MessageMatcherUnregister(appID, name)
+}
+
+func (r *Router) MessageMatcherRegister(appID string, matcher *MessageMatcher) error {
+	if appID == "" || matcher == nil || matcher.Name == "" {
+		return common.ErrBotRecvMsgRegister.ErrorWithExtStr(
+			fmt.Sprintf("params is empty or nil. AppID[%s]MatcherIsNil[%t]", appID, matcher == nil))
+	}
+	if matcher.Handler == nil {
+		return common.ErrBotRecvMsgRegister.ErrorWithExtStr(fmt.Sprintf("matcher[%s] handler is nil", matcher.Name))
+	}
+	if matcher.Regexp == nil && matcher.Prefix == "" && matcher.Predicate == nil {
+		return common.ErrBotRecvMsgRegister.ErrorWithExtStr(fmt.Sprintf("matcher[%s] has no condition", matcher.Name))
+	}
+
+	if !r.matcherManager.Add(appID, matcher) {
+		return common.ErrBotRecvMsgRegister.ErrorWithExtStr(fmt.Sprintf("appid[%s]matcher[%s] has been registered", appID, matcher.Name))
+	}
+	return nil
+}
+
+func (r *Router) MessageMatcherUnregister(appID, name string) error {
+	if appID == "" || name == "" {
+		return common.ErrBotRecvMsgRegister.ErrorWithExtStr(fmt.Sprintf("params is empty. AppID[%s]Matcher[%s]", appID, name))
+	}
+
+	if !r.matcherManager.Delete(appID, name) {
+		return common.ErrBotRecvMsgHandlerNoFound.ErrorWithExtStr(fmt.Sprintf("appid[%s]matcher[%s]", appID, name))
+	}
+	return nil
+}
+
+// matcherHandler the handler of the first matched matcher, and set msg.TextParam and msg.MatchGroups
+func (r *Router) matcherHandler(ctx context.Context, msg *protocol.BotRecvMsg) (string, HandlerBotMsg) {
+	matcher, textParam, groups := r.matcherManager.Match(ctx, msg, strings.TrimSpace(msg.TextParam))
+	if matcher == nil {
+		return "", nil
+	}
+
+	msg.TextParam = textParam
+	msg.MatchGroups = groups
+	return matcher.Name, matcher.Handler
+}
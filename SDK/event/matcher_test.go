@@ -0,0 +1,94 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event_test
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+func TestMessageMatcher(t *testing.T) {
+	appID := "cli_test_matcher"
+	router := event.NewRouter()
+
+	called := ""
+	record := func(name string) event.HandlerBotMsg {
+		return func(ctx context.Context, msg *protocol.BotRecvMsg) error {
+			called = name + ":" + msg.TextParam
+			if ticket, ok := msg.MatchGroups["ticket"]; ok {
+				called += ":" + ticket
+			}
+			return nil
+		}
+	}
+
+	router.BotRecvMsgRegister(appID, protocol.CmdDefault, record("default"))
+	router.BotRecvMsgRegister(appID, "deploy", record("command"))
+	router.MessageMatcherRegister(appID, &event.MessageMatcher{
+		Name:    "ticket",
+		Regexp:  regexp.MustCompile(`(?P<ticket>[A-Z]+-\d+)`),
+		Handler: record("ticket"),
+	})
+	router.MessageMatcherRegister(appID, &event.MessageMatcher{
+		Name:     "slash",
+		Prefix:   "/ship",
+		Priority: 10,
+		Handler:  record("slash"),
+	})
+	router.MessageMatcherRegister(appID, &event.MessageMatcher{
+		Name:   "question",
+		Prefix: "how",
+		Predicate: func(ctx context.Context, msg *protocol.BotRecvMsg) bool {
+			return strings.HasSuffix(msg.TextParam, "?")
+		},
+		Handler: record("question"),
+	})
+
+	ctx := context.Background()
+	cases := []struct {
+		text     string
+		expected string
+	}{
+		{"deploy JIRA-1", "command:JIRA-1"}, // the command first
+		{"look at JIRA-12 please", "ticket:look at JIRA-12 please:JIRA-12"},
+		{"/SHIP JIRA-3 now", "slash:JIRA-3 now"}, // the higher priority first
+		{"How to deploy?", "question:to deploy?"},
+		{"how to deploy", "default:how to deploy"}, // the predicate is not matched
+		{"hello", "default:hello"},
+	}
+	for _, c := range cases {
+		called = ""
+		err := router.BotRecvMsgHandler(ctx, newTestTextMsg(appID, c.text))
+		if err != nil || called != c.expected {
+			t.Errorf("text[%s]: called[%s]err[%v]", c.text, called, err)
+		}
+	}
+
+	// the dispatch falls back to the default command after the matcher is removed
+	if err := router.MessageMatcherUnregister(appID, "ticket"); err != nil {
+		t.Errorf("unregister: err[%v]", err)
+	}
+	router.BotRecvMsgHandler(ctx, newTestTextMsg(appID, "look at JIRA-12"))
+	if called != "default:look at JIRA-12" {
+		t.Errorf("after unregister: called[%s]", called)
+	}
+
+	invalid := []*event.MessageMatcher{
+		nil,
+		{Name: "no_handler", Prefix: "/a"},
+		{Name: "no_condition", Handler: record("x")},
+		{Name: "slash", Prefix: "/b", Handler: record("x")},
+	}
+	for i, matcher := range invalid {
+		if err := router.MessageMatcherRegister(appID, matcher); err == nil {
+			t.Errorf("invalid[%d]: err is nil", i)
+		}
+	}
+}
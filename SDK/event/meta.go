@@ -0,0 +1,104 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"context"
+
+	"github.com/bitly/go-simplejson"
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+const (
+	eventMetaContextKey = "botframework_event_meta"
+)
+
+// EventMeta the metadata of the event being handled, the fields are empty if the event doesn't have them
+type EventMeta struct {
+	EventID   string
+	EventType string
+	AppID     string
+	TenantKey string
+	ChatID    string
+	ChatType  string // schema 1.0: private/group, schema 2.0: p2p/group
+	OpenID    string
+	MessageID string
+}
+
+// the json paths of schema 1.0 and 2.0, the first non-empty one is used
+var (
+	metaChatIDPaths    = [][]string{{"open_chat_id"}, {"chat_id"}, {"message", "chat_id"}}
+	metaChatTypePaths  = [][]string{{"chat_type"}, {"message", "chat_type"}}
+	metaOpenIDPaths    = [][]string{{"open_id"}, {"user_open_id"}, {"sender", "sender_id", "open_id"}, {"operator", "open_id"}}
+	metaMessageIDPaths = [][]string{{"open_message_id"}, {"message", "message_id"}}
+)
+
+// MetaFromContext get the metadata in the event handler, bot command handler and card action handler
+func MetaFromContext(ctx context.Context) *EventMeta {
+	meta, ok := ctx.Value(eventMetaContextKey).(*EventMeta)
+	if !ok {
+		return nil
+	}
+	return meta
+}
+
+func newEventMeta(header *protocol.EventHeader, eventBody []byte) *EventMeta {
+	meta := &EventMeta{
+		EventID:   header.EventID,
+		EventType: header.EventType,
+		AppID:     header.AppID,
+		TenantKey: header.TenantKey,
+	}
+
+	jsonBody, err := simplejson.NewJson(eventBody)
+	if err != nil {
+		return meta
+	}
+	meta.ChatID = getJsonString(jsonBody, metaChatIDPaths)
+	meta.ChatType = getJsonString(jsonBody, metaChatTypePaths)
+	meta.OpenID = getJsonString(jsonBody, metaOpenIDPaths)
+	meta.MessageID = getJsonString(jsonBody, metaMessageIDPaths)
+
+	return meta
+}
+
+func newCardMeta(appID string, callback *protocol.CardCallbackForm) *EventMeta {
+	return &EventMeta{
+		AppID:     appID,
+		TenantKey: callback.TenantKey,
+		OpenID:    callback.OpenID,
+		MessageID: callback.OpenMessageID,
+	}
+}
+
+// withEventMeta put the metadata into ctx, and each field as a log field(see common.EventLogFields)
+func withEventMeta(ctx context.Context, meta *EventMeta) context.Context {
+	ctx = context.WithValue(ctx, eventMetaContextKey, meta)
+
+	for ctxKey, value := range map[string]string{
+		common.CtxKeyEventID:   meta.EventID,
+		common.CtxKeyEventType: meta.EventType,
+		common.CtxKeyAppID:     meta.AppID,
+		common.CtxKeyTenantKey: meta.TenantKey,
+		common.CtxKeyChatID:    meta.ChatID,
+		common.CtxKeyOpenID:    meta.OpenID,
+		common.CtxKeyMessageID: meta.MessageID,
+	} {
+		if value != "" {
+			ctx = context.WithValue(ctx, ctxKey, value)
+		}
+	}
+	return ctx
+}
+
+func getJsonString(jsonBody *simplejson.Json, paths [][]string) string {
+	for _, path := range paths {
+		if value, err := jsonBody.GetPath(path...).String(); err == nil && value != "" {
+			return value
+		}
+	}
+	return ""
+}
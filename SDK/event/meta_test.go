@@ -0,0 +1,80 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+	"github.com/sirupsen/logrus"
+)
+
+func TestMetaFromContext(t *testing.T) {
+	appID := "cli_test_event_meta"
+	initTestApp(appID)
+
+	var meta *event.EventMeta
+	var fields logrus.Fields
+	handler := func(ctx context.Context, eventBody []byte) error {
+		meta = event.MetaFromContext(ctx)
+		if entry, ok := common.NewCommonLogger().GetLogger(ctx).(*logrus.Entry); ok {
+			fields = entry.Data
+		}
+		return nil
+	}
+	event.EventRegister(appID, protocol.EventTypeMessage, handler)
+	event.EventRegister(appID, "im.message.receive_v1", handler)
+
+	ctx := context.Background()
+
+	// schema 1.0
+	body := fmt.Sprintf(`{"uuid":"meta_1","token":"%s","type":"event_callback","event":{"type":"message","app_id":"%s","tenant_key":"tenant","open_chat_id":"oc_1","open_id":"ou_1","open_message_id":"om_1"}}`,
+		testVerifyToken, appID)
+	_, err := event.EventCallback(ctx, body, appID)
+	expect := event.EventMeta{EventID: "meta_1", EventType: "message", AppID: appID, TenantKey: "tenant", ChatID: "oc_1", OpenID: "ou_1", MessageID: "om_1"}
+	if err != nil || meta == nil || *meta != expect {
+		t.Errorf("schema 1.0: meta[%+v]err[%v]", meta, err)
+	}
+	if fields["event_id"] != "meta_1" || fields["chat_id"] != "oc_1" || fields["app_id"] != appID {
+		t.Errorf("log fields: %v", fields)
+	}
+
+	// schema 2.0
+	body = fmt.Sprintf(`{"schema":"2.0","header":{"event_id":"meta_2","event_type":"im.message.receive_v1","token":"%s","app_id":"%s","tenant_key":"tenant"},`+
+		`"event":{"sender":{"sender_id":{"open_id":"ou_2"}},"message":{"message_id":"om_2","chat_id":"oc_2"}}}`, testVerifyToken, appID)
+	_, err = event.EventCallback(ctx, body, appID)
+	expect = event.EventMeta{EventID: "meta_2", EventType: "im.message.receive_v1", AppID: appID, TenantKey: "tenant", ChatID: "oc_2", OpenID: "ou_2", MessageID: "om_2"}
+	if err != nil || meta == nil || *meta != expect {
+		t.Errorf("schema 2.0: meta[%+v]err[%v]", meta, err)
+	}
+}
+
+func TestMetaRequestID(t *testing.T) {
+	appID := "cli_test_event_meta_request_id"
+	initTestApp(appID)
+
+	var requestID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID = r.Header.Get(common.HeaderRequestID)
+	}))
+	defer server.Close()
+
+	// the open platform calls in the handler carry the event id
+	event.EventRegister(appID, protocol.EventTypeMessage, func(ctx context.Context, eventBody []byte) error {
+		_, _, err := common.DoHttpWithContext(ctx, common.HTTPMethodGet, server.URL, nil, new(bytes.Buffer))
+		return err
+	})
+	_, err := event.EventCallback(context.Background(), newTestEventBody(appID, "meta_request_id", protocol.EventTypeMessage), appID)
+	if err != nil || requestID != "meta_request_id" {
+		t.Errorf("requestID[%s]err[%v]", requestID, err)
+	}
+}
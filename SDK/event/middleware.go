@@ -0,0 +1,336 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+// EventMiddleware wrap EventHandler, demo:
+//
+// func TraceMiddleware(next event.EventHandler) event.EventHandler {
+// 	return func(ctx context.Context, eventBody []byte) error {
+// 		// before handler
+// 		err := next(ctx, eventBody)
+// 		// after handler
+// 		return err
+// 	}
+// }
+type EventMiddleware func(next EventHandler) EventHandler
+
+// BotMsgMiddleware wrap HandlerBotMsg
+type BotMsgMiddleware func(next HandlerBotMsg) HandlerBotMsg
+
+// ActionMiddleware wrap ActionMethod
+type ActionMiddleware func(next ActionMethod) ActionMethod
+
+// TimingReporter report the cost of the handler, eg: metrics
+type TimingReporter func(ctx context.Context, cost time.Duration, err error)
+
+// MiddlewareManager the global middlewares run before the app middlewares,
+// the middleware registered first runs first.
+type MiddlewareManager struct {
+	eventGlobal  []EventMiddleware
+	eventApp     map[string][]EventMiddleware
+	botMsgGlobal []BotMsgMiddleware
+	botMsgApp    map[string][]BotMsgMiddleware
+	actionGlobal []ActionMiddleware
+	actionApp    map[string][]ActionMiddleware
+	rwMu         sync.RWMutex
+}
+
+func (m *MiddlewareManager) WrapEvent(appID string, handler EventHandler) EventHandler {
+	// the slices are only appended, the snapshot is not changed
+	m.rwMu.RLock()
+	mws, global := m.eventApp[appID], m.eventGlobal
+	m.rwMu.RUnlock()
+
+	for i := len(mws) - 1; i >= 0; i-- {
+		handler = mws[i](handler)
+	}
+	for i := len(global) - 1; i >= 0; i-- {
+		handler = global[i](handler)
+	}
+	return handler
+}
+
+func (m *MiddlewareManager) WrapBotMsg(appID string, handler HandlerBotMsg) HandlerBotMsg {
+	// the slices are only appended, the snapshot is not changed
+	m.rwMu.RLock()
+	mws, global := m.botMsgApp[appID], m.botMsgGlobal
+	m.rwMu.RUnlock()
+
+	for i := len(mws) - 1; i >= 0; i-- {
+		handler = mws[i](handler)
+	}
+	for i := len(global) - 1; i >= 0; i-- {
+		handler = global[i](handler)
+	}
+	return handler
+}
+
+func (m *MiddlewareManager) WrapAction(appID string, handler ActionMethod) ActionMethod {
+	// the slices are only appended, the snapshot is not changed
+	m.rwMu.RLock()
+	mws, global := m.actionApp[appID], m.actionGlobal
+	m.rwMu.RUnlock()
+
+	for i := len(mws) - 1; i >= 0; i-- {
+		handler = mws[i](handler)
+	}
+	for i := len(global) - 1; i >= 0; i-- {
+		handler = global[i](handler)
+	}
+	return handler
+}
+
+var middlewareManager *MiddlewareManager
+
+func init() {
+	middlewareManager = &MiddlewareManager{
+		eventApp:  make(map[string][]EventMiddleware, 0),
+		botMsgApp: make(map[string][]BotMsgMiddleware, 0),
+		actionApp: make(map[string][]ActionMiddleware, 0),
+	}
+}
+
+// UseEventMiddleware middlewares for all apps, demo:
+// event.UseEventMiddleware(event.RecoveryEventMiddleware(), event.LoggingEventMiddleware())
+func UseEventMiddleware(mws ...EventMiddleware) {
+	middlewareManager.rwMu.Lock()
+	defer middlewareManager.rwMu.Unlock()
+
+	for _, mw := range mws {
+		if mw != nil {
+			middlewareManager.eventGlobal = append(middlewareManager.eventGlobal, mw)
+		}
+	}
+}
+
+// UseAppEventMiddleware middlewares for one app
+func UseAppEventMiddleware(appID string, mws ...EventMiddleware) error {
+	if appID == "" {
+		return common.ErrEventTypeRegister.ErrorWithExtStr("middleware appID is empty")
+	}
+
+	middlewareManager.rwMu.Lock()
+	defer middlewareManager.rwMu.Unlock()
+
+	for _, mw := range mws {
+		if mw != nil {
+			middlewareManager.eventApp[appID] = append(middlewareManager.eventApp[appID], mw)
+		}
+	}
+	return nil
+}
+
+// UseBotMsgMiddleware middlewares of bot command handler for all apps
+func UseBotMsgMiddleware(mws ...BotMsgMiddleware) {
+	middlewareManager.rwMu.Lock()
+	defer middlewareManager.rwMu.Unlock()
+
+	for _, mw := range mws {
+		if mw != nil {
+			middlewareManager.botMsgGlobal = append(middlewareManager.botMsgGlobal, mw)
+		}
+	}
+}
+
+// UseAppBotMsgMiddleware middlewares of bot command handler for one app
+func UseAppBotMsgMiddleware(appID string, mws ...BotMsgMiddleware) error {
+	if appID == "" {
+		return common.ErrBotRecvMsgRegister.ErrorWithExtStr("middleware appID is empty")
+	}
+
+	middlewareManager.rwMu.Lock()
+	defer middlewareManager.rwMu.Unlock()
+
+	for _, mw := range mws {
+		if mw != nil {
+			middlewareManager.botMsgApp[appID] = append(middlewareManager.botMsgApp[appID], mw)
+		}
+	}
+	return nil
+}
+
+// UseActionMiddleware middlewares of card action handler for all apps
+func UseActionMiddleware(mws ...ActionMiddleware) {
+	middlewareManager.rwMu.Lock()
+	defer middlewareManager.rwMu.Unlock()
+
+	for _, mw := range mws {
+		if mw != nil {
+			middlewareManager.actionGlobal = append(middlewareManager.actionGlobal, mw)
+		}
+	}
+}
+
+// UseAppActionMiddleware middlewares of card action handler for one app
+func UseAppActionMiddleware(appID string, mws ...ActionMiddleware) error {
+	if appID == "" {
+		return common.ErrCardMethodRegister.ErrorWithExtStr("middleware appID is empty")
+	}
+
+	middlewareManager.rwMu.Lock()
+	defer middlewareManager.rwMu.Unlock()
+
+	for _, mw := range mws {
+		if mw != nil {
+			middlewareManager.actionApp[appID] = append(middlewareManager.actionApp[appID], mw)
+		}
+	}
+	return nil
+}
+
+// RecoveryEventMiddleware recover the panic of handler, and return it as error
+func RecoveryEventMiddleware() EventMiddleware {
+	return func(next EventHandler) EventHandler {
+		return func(ctx context.Context, eventBody []byte) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = recoveredError(ctx, r)
+				}
+			}()
+
+			return next(ctx, eventBody)
+		}
+	}
+}
+
+// LoggingEventMiddleware log the result of handler
+func LoggingEventMiddleware() EventMiddleware {
+	return func(next EventHandler) EventHandler {
+		return func(ctx context.Context, eventBody []byte) error {
+			start := time.Now()
+			err := next(ctx, eventBody)
+			if err != nil {
+				common.Logger(ctx).Errorf("eventHandler: error[%v]cost[%v]", err, time.Since(start))
+			} else {
+				common.Logger(ctx).Infof("eventHandler: success cost[%v]", time.Since(start))
+			}
+			return err
+		}
+	}
+}
+
+// TimingEventMiddleware report the cost of handler
+func TimingEventMiddleware(report TimingReporter) EventMiddleware {
+	return func(next EventHandler) EventHandler {
+		return func(ctx context.Context, eventBody []byte) error {
+			start := time.Now()
+			err := next(ctx, eventBody)
+			if report != nil {
+				report(ctx, time.Since(start), err)
+			}
+			return err
+		}
+	}
+}
+
+// RecoveryBotMsgMiddleware recover the panic of handler, and return it as error
+func RecoveryBotMsgMiddleware() BotMsgMiddleware {
+	return func(next HandlerBotMsg) HandlerBotMsg {
+		return func(ctx context.Context, msg *protocol.BotRecvMsg) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = recoveredError(ctx, r)
+				}
+			}()
+
+			return next(ctx, msg)
+		}
+	}
+}
+
+// LoggingBotMsgMiddleware log the result of handler
+func LoggingBotMsgMiddleware() BotMsgMiddleware {
+	return func(next HandlerBotMsg) HandlerBotMsg {
+		return func(ctx context.Context, msg *protocol.BotRecvMsg) error {
+			start := time.Now()
+			err := next(ctx, msg)
+			if err != nil {
+				common.Logger(ctx).Errorf("botMsgHandler: error[%v]appid[%s]chatID[%s]messageID[%s]cost[%v]",
+					err, msg.AppID, msg.OpenChatID, msg.OpenMessageID, time.Since(start))
+			} else {
+				common.Logger(ctx).Infof("botMsgHandler: success appid[%s]chatID[%s]messageID[%s]cost[%v]",
+					msg.AppID, msg.OpenChatID, msg.OpenMessageID, time.Since(start))
+			}
+			return err
+		}
+	}
+}
+
+// TimingBotMsgMiddleware report the cost of handler
+func TimingBotMsgMiddleware(report TimingReporter) BotMsgMiddleware {
+	return func(next HandlerBotMsg) HandlerBotMsg {
+		return func(ctx context.Context, msg *protocol.BotRecvMsg) error {
+			start := time.Now()
+			err := next(ctx, msg)
+			if report != nil {
+				report(ctx, time.Since(start), err)
+			}
+			return err
+		}
+	}
+}
+
+// RecoveryActionMiddleware recover the panic of handler, and return it as error
+func RecoveryActionMiddleware() ActionMiddleware {
+	return func(next ActionMethod) ActionMethod {
+		return func(ctx context.Context, cardCallback *protocol.CardCallbackForm) (card *protocol.CardForm, err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					card, err = nil, recoveredError(ctx, r)
+				}
+			}()
+
+			return next(ctx, cardCallback)
+		}
+	}
+}
+
+// LoggingActionMiddleware log the result of handler
+func LoggingActionMiddleware() ActionMiddleware {
+	return func(next ActionMethod) ActionMethod {
+		return func(ctx context.Context, cardCallback *protocol.CardCallbackForm) (*protocol.CardForm, error) {
+			start := time.Now()
+			card, err := next(ctx, cardCallback)
+			if err != nil {
+				common.Logger(ctx).Errorf("actionHandler: error[%v]method[%s]messageID[%s]cost[%v]",
+					err, cardCallback.Action.Value["method"], cardCallback.OpenMessageID, time.Since(start))
+			} else {
+				common.Logger(ctx).Infof("actionHandler: success method[%s]messageID[%s]cost[%v]",
+					cardCallback.Action.Value["method"], cardCallback.OpenMessageID, time.Since(start))
+			}
+			return card, err
+		}
+	}
+}
+
+// TimingActionMiddleware report the cost of handler
+func TimingActionMiddleware(report TimingReporter) ActionMiddleware {
+	return func(next ActionMethod) ActionMethod {
+		return func(ctx context.Context, cardCallback *protocol.CardCallbackForm) (*protocol.CardForm, error) {
+			start := time.Now()
+			card, err := next(ctx, cardCallback)
+			if report != nil {
+				report(ctx, time.Since(start), err)
+			}
+			return card, err
+		}
+	}
+}
+
+func recoveredError(ctx context.Context, r interface{}) error {
+	common.Logger(ctx).Errorf("Recover: panic:%v, stack info:%v", r, string(debug.Stack()))
+	return common.ErrEventHandlerPanic.ErrorWithExtStr(fmt.Sprintf("%v", r))
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+func TestEventMiddleware(t *testing.T) {
+	appID := "cli_test_event_middleware"
+	initTestApp(appID)
+
+	var trace []string
+	tag := func(name string) event.EventMiddleware {
+		return func(next event.EventHandler) event.EventHandler {
+			return func(ctx context.Context, eventBody []byte) error {
+				trace = append(trace, name+"_before")
+				err := next(ctx, eventBody)
+				trace = append(trace, name+"_after")
+				return err
+			}
+		}
+	}
+
+	var reported error
+	event.UseAppEventMiddleware(appID,
+		event.TimingEventMiddleware(func(ctx context.Context, cost time.Duration, err error) { reported = err }),
+		event.RecoveryEventMiddleware(),
+		tag("first"),
+		tag("second"),
+	)
+	event.EventRegister(appID, protocol.EventTypeAddBot, func(ctx context.Context, eventBody []byte) error {
+		trace = append(trace, "handler")
+		return nil
+	})
+	event.EventRegister(appID, protocol.EventTypeRemoveBot, func(ctx context.Context, eventBody []byte) error {
+		panic("remove bot panic")
+	})
+
+	ctx := context.Background()
+	_, err := event.EventCallback(ctx, newTestEventBody(appID, "mw_1", protocol.EventTypeAddBot), appID)
+	if err != nil {
+		t.Fatalf("EventCallback failed: err[%v]", err)
+	}
+	want := "first_before,second_before,handler,second_after,first_after"
+	if strings.Join(trace, ",") != want {
+		t.Errorf("middleware order: trace[%v]", trace)
+	}
+
+	// panic is recovered as error
+	_, err = event.EventCallback(ctx, newTestEventBody(appID, "mw_2", protocol.EventTypeRemoveBot), appID)
+	if err == nil || reported == nil || !strings.Contains(reported.Error(), "remove bot panic") {
+		t.Errorf("recovery middleware: err[%v]reported[%v]", err, reported)
+	}
+}
+
+func TestBotMsgMiddleware(t *testing.T) {
+	appID := "cli_test_botmsg_middleware"
+
+	event.UseAppBotMsgMiddleware(appID, func(next event.HandlerBotMsg) event.HandlerBotMsg {
+		return func(ctx context.Context, msg *protocol.BotRecvMsg) error {
+			if msg.OpenID != "ou_admin" {
+				return fmt.Errorf("permission denied")
+			}
+			return next(ctx, msg)
+		}
+	})
+
+	called := 0
+	event.BotRecvMsgRegister(appID, "deploy", func(ctx context.Context, msg *protocol.BotRecvMsg) error {
+		called++
+		return nil
+	})
+
+	msg := `{"type":"message","app_id":"%s","msg_type":"text","open_id":"%s","text_without_at_bot":"deploy v1"}`
+	err := event.BotRecvMsgHandler(context.Background(), []byte(fmt.Sprintf(msg, appID, "ou_guest")))
+	if err == nil || called != 0 {
+		t.Errorf("middleware should reject: called[%d]err[%v]", called, err)
+	}
+
+	err = event.BotRecvMsgHandler(context.Background(), []byte(fmt.Sprintf(msg, appID, "ou_admin")))
+	if err != nil || called != 1 {
+		t.Errorf("middleware should pass: called[%d]err[%v]", called, err)
+	}
+}
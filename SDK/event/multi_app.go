@@ -0,0 +1,139 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/larksuite/botframework-go/SDK/appconfig"
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+// routingPayload the fields used to find the app of the callback
+type routingPayload struct {
+	Encrypt string                  `json:"encrypt"`
+	Token   string                  `json:"token"`
+	Schema  string                  `json:"schema"`
+	Header  *protocol.EventHeaderV2 `json:"header"`
+	Event   struct {
+		AppID string `json:"app_id"`
+	} `json:"event"`
+}
+
+// EventCallbackAuto one endpoint for all apps registered by appconfig.Init, the app is resolved from the payload(see ResolveEventAppID)
+func EventCallbackAuto(ctx context.Context, body string, header map[string]string) (string, error) {
+	return defaultRouter.EventCallbackAuto(ctx, body, header)
+}
+
+// CardCallBackAuto one endpoint for all apps registered by appconfig.Init, the app is resolved from the payload(see ResolveCardAppID)
+func CardCallBackAuto(ctx context.Context, header map[string]string, body []byte) (*protocol.CardForm, string, error) {
+	return defaultRouter.CardCallBackAuto(ctx, header, body)
+}
+
+func (r *Router) EventCallbackAuto(ctx context.Context, body string, header map[string]string) (string, error) {
+	appID, err := ResolveEventAppID(body)
+	if err != nil {
+		return "", err
+	}
+
+	return r.EventCallbackWithHeader(ctx, body, appID, header)
+}
+
+func (r *Router) CardCallBackAuto(ctx context.Context, header map[string]string, body []byte) (*protocol.CardForm, string, error) {
+	appID, err := ResolveCardAppID(ctx, header, body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return r.CardCallBack(ctx, appID, header, body)
+}
+
+// ResolveEventAppID find the app of the event callback:
+// 1. encrypted payload: decrypt it by the EncryptKey of each app, the app whose VerifyToken matches the decrypted token
+// 2. plaintext payload: header.app_id(schema 2.0) or event.app_id(schema 1.0)
+// 3. plaintext url_verification: the app whose VerifyToken matches the token
+func ResolveEventAppID(body string) (string, error) {
+	payload := &routingPayload{}
+	err := json.Unmarshal([]byte(body), payload)
+	if err != nil {
+		return "", common.ErrEventGetBase.ErrorWithExtErr(err)
+	}
+
+	appConfs := appconfig.GetAllConfig()
+
+	if payload.Encrypt != "" {
+		for _, appConf := range appConfs {
+			if appConf.EncryptKey == "" {
+				continue
+			}
+
+			content, err := eventDataDecrypter(body, appConf.EncryptKey)
+			if err != nil {
+				continue
+			}
+			var callbackBase protocol.CallbackBase
+			if json.Unmarshal([]byte(content), &callbackBase) != nil {
+				continue
+			}
+			if callbackBase.GetToken() == appConf.VerifyToken {
+				return appConf.AppID, nil
+			}
+		}
+
+		return "", common.ErrAppConfNotFound.ErrorWithExtStr("no encrypt key can decrypt the event")
+	}
+
+	if payload.Schema == protocol.EventSchemaV2 && payload.Header != nil && payload.Header.AppID != "" {
+		return payload.Header.AppID, nil
+	}
+	if payload.Event.AppID != "" {
+		return payload.Event.AppID, nil
+	}
+
+	for _, appConf := range appConfs {
+		if appConf.EncryptKey == "" && payload.Token != "" && payload.Token == appConf.VerifyToken {
+			return appConf.AppID, nil
+		}
+	}
+
+	return "", common.ErrAppConfNotFound.ErrorWithExtStr("cannot resolve app id from the event")
+}
+
+// ResolveCardAppID find the app of the card callback:
+// 1. the appid of the payload
+// 2. the app whose VerifyToken matches the signature
+// 3. the app whose VerifyToken matches the token of the payload
+func ResolveCardAppID(ctx context.Context, header map[string]string, body []byte) (string, error) {
+	f := &protocol.CardChallenge{}
+	err := json.Unmarshal(body, f)
+	if err != nil {
+		return "", common.ErrJsonUnmarshal.ErrorWithExtErr(err)
+	}
+	if f.AppID != "" {
+		return f.AppID, nil
+	}
+
+	appConfs := appconfig.GetAllConfig()
+
+	if header[HeaderSignature] != "" {
+		for _, appConf := range appConfs {
+			if verifySignature(ctx, appConf.VerifyToken, header, body) == nil {
+				return appConf.AppID, nil
+			}
+		}
+	}
+
+	if f.Token != "" {
+		for _, appConf := range appConfs {
+			if f.Token == appConf.VerifyToken {
+				return appConf.AppID, nil
+			}
+		}
+	}
+
+	return "", common.ErrAppConfNotFound.ErrorWithExtStr("cannot resolve app id from the card callback")
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event_test
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/larksuite/botframework-go/SDK/appconfig"
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+func TestEventCallbackAuto(t *testing.T) {
+	plainAppID := "cli_test_auto_plain"
+	encryptAppID := "cli_test_auto_encrypt"
+	encryptKey := "auto_encrypt_key"
+	encryptToken := "auto_encrypt_token"
+	initTestApp(plainAppID)
+	appconfig.Init(appconfig.AppConfig{
+		AppID:       encryptAppID,
+		AppType:     protocol.InternalApp,
+		VerifyToken: encryptToken,
+		EncryptKey:  encryptKey,
+	})
+
+	called := map[string]int{}
+	for _, appID := range []string{plainAppID, encryptAppID} {
+		appID := appID
+		event.EventRegister(appID, protocol.EventTypeAddBot, func(ctx context.Context, eventBody []byte) error {
+			called[appID]++
+			return nil
+		})
+	}
+
+	ctx := context.Background()
+
+	// plaintext
+	_, err := event.EventCallbackAuto(ctx, newTestEventBody(plainAppID, "auto_1", protocol.EventTypeAddBot), nil)
+	if err != nil || called[plainAppID] != 1 {
+		t.Errorf("plaintext: called[%v]err[%v]", called, err)
+	}
+
+	// encrypted
+	body := strings.Replace(newTestEventBody(encryptAppID, "auto_2", protocol.EventTypeAddBot), testVerifyToken, encryptToken, 1)
+	_, err = event.EventCallbackAuto(ctx, encryptTestEvent(body, encryptKey), nil)
+	if err != nil || called[encryptAppID] != 1 {
+		t.Errorf("encrypted: called[%v]err[%v]", called, err)
+	}
+
+	// encrypted challenge
+	body = fmt.Sprintf(`{"challenge":"abc","token":"%s","type":"url_verification"}`, encryptToken)
+	challenge, err := event.EventCallbackAuto(ctx, encryptTestEvent(body, encryptKey), nil)
+	if err != nil || challenge != "abc" {
+		t.Errorf("encrypted challenge: challenge[%s]err[%v]", challenge, err)
+	}
+
+	// unknown encrypt key
+	_, err = event.EventCallbackAuto(ctx, encryptTestEvent(body, "unknown_key"), nil)
+	if err == nil {
+		t.Errorf("unknown encrypt key: err is nil")
+	}
+}
+
+func TestCardCallBackAuto(t *testing.T) {
+	appID := "cli_test_auto_card"
+	verifyToken := "auto_card_token"
+	appconfig.Init(appconfig.AppConfig{
+		AppID:       appID,
+		AppType:     protocol.InternalApp,
+		VerifyToken: verifyToken,
+	})
+	event.CardRegister(appID, "auto_click", func(ctx context.Context, cardCallback *protocol.CardCallbackForm) (*protocol.CardForm, error) {
+		return &protocol.CardForm{OpenIDs: []string{cardCallback.OpenID}}, nil
+	})
+
+	ctx := context.Background()
+
+	// challenge with appid
+	_, challenge, err := event.CardCallBackAuto(ctx, map[string]string{"": ""},
+		[]byte(fmt.Sprintf(`{"appid":"%s","challenge":"abc","token":"%s","type":"url_verification"}`, appID, verifyToken)))
+	if err != nil || challenge != "abc" {
+		t.Errorf("challenge: challenge[%s]err[%v]", challenge, err)
+	}
+
+	// action with signature
+	body := []byte(`{"open_id":"ou_123","action":{"value":{"method":"auto_click","sid":"1"}}}`)
+	header := map[string]string{
+		event.HeaderRequestTimestamp: "1577808000",
+		event.HeaderRequestNonce:     "nonce",
+		event.HeaderSignature:        fmt.Sprintf("%x", sha1.Sum([]byte("1577808000"+"nonce"+verifyToken+string(body)))),
+	}
+	resolved, err := event.ResolveCardAppID(ctx, header, body)
+	if err != nil || resolved != appID {
+		t.Errorf("resolve: appID[%s]err[%v]", resolved, err)
+	}
+	card, _, err := event.CardCallBackAuto(ctx, header, body)
+	if err != nil || card == nil || len(card.OpenIDs) != 1 {
+		t.Errorf("action: card[%v]err[%v]", card, err)
+	}
+
+	// unknown signature
+	header[event.HeaderSignature] = "invalid"
+	_, _, err = event.CardCallBackAuto(ctx, header, body)
+	if err == nil {
+		t.Errorf("unknown signature: err is nil")
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+// ParsePostText parse the text of the post message event to the rich text content, eg:
+// <p>deploy <a href="https://example.com">v1.2</a> <at open_id="ou_xxx">@Tom</at></p><p><img src="..." origin-width="888" origin-height="492"/></p>
+// Each paragraph is a line of the content. The images are matched to imageKeys in order.
+func ParsePostText(text string, imageKeys []string) (*protocol.RichTextContent, error) {
+	decoder := xml.NewDecoder(strings.NewReader("<post>" + text + "</post>"))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	content := protocol.RichTextContent{}
+	var line []protocol.RichTextElementForm
+	var current *protocol.RichTextElementForm // the <a>/<at> collecting the text
+	imageIndex := 0
+
+	flush := func() {
+		if len(line) != 0 {
+			content = append(content, line)
+			line = nil
+		}
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p", "figure", "br":
+				flush()
+			case "a":
+				current = &protocol.RichTextElementForm{Tag: "a", Text: new(string), Href: xmlAttr(t, "href")}
+			case "at":
+				current = &protocol.RichTextElementForm{Tag: "at", Text: new(string), UserID: xmlAttr(t, "open_id")}
+			case "img":
+				image := protocol.RichTextElementForm{Tag: "img"}
+				if imageIndex < len(imageKeys) {
+					image.ImageKey = imageKeys[imageIndex]
+				}
+				imageIndex++
+				width, _ := strconv.Atoi(xmlAttr(t, "origin-width"))
+				height, _ := strconv.Atoi(xmlAttr(t, "origin-height"))
+				image.Width, image.Height = int32(width), int32(height)
+				line = append(line, image)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p", "figure":
+				flush()
+			case "a", "at":
+				if current != nil {
+					line = append(line, *current)
+					current = nil
+				}
+			}
+		case xml.CharData:
+			s := string(t)
+			if s == "" {
+				continue
+			}
+			if current != nil {
+				*current.Text += s
+				continue
+			}
+			// merge the text split by the formatting tags, eg: <b>
+			if n := len(line); n > 0 && line[n-1].Tag == "text" {
+				*line[n-1].Text += s
+				continue
+			}
+			line = append(line, protocol.RichTextElementForm{Tag: "text", Text: &s})
+		}
+	}
+	flush()
+
+	return &content, nil
+}
+
+func xmlAttr(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// FlattenRichText render the content to plain text, one line per paragraph:
+// the link is its text(or href if the text is empty), the mention is <at open_id="ou_xxx">@name</at> as the text message,
+// the image is dropped.
+func FlattenRichText(content *protocol.RichTextContent) string {
+	if content == nil {
+		return ""
+	}
+
+	lines := make([]string, 0, len(*content))
+	for _, elements := range *content {
+		var b strings.Builder
+		for _, element := range elements {
+			text := ""
+			if element.Text != nil {
+				text = *element.Text
+			}
+
+			switch element.Tag {
+			case "text":
+				b.WriteString(text)
+			case "a":
+				if text == "" {
+					text = element.Href
+				}
+				b.WriteString(text)
+			case "at":
+				b.WriteString(fmt.Sprintf(`<at open_id="%s">%s</at>`, element.UserID, text))
+			}
+		}
+
+		if line := strings.TrimSpace(b.String()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// postTextWithoutAtBot flatten the post to the text for the commands.
+// The bot mention is stripped by the open platform in text_without_at_bot, or is the first mention of botOpenID in text.
+// The mentions are kept if botOpenID is empty, see appconfig.AppConfig.BotOpenID.
+func postTextWithoutAtBot(msgEvent *protocol.PostMsgEvent, richText *protocol.RichTextContent, botOpenID string) (string, error) {
+	if msgEvent.TextWithoutAtBot != "" {
+		content, err := ParsePostText(msgEvent.TextWithoutAtBot, msgEvent.ImageKeys)
+		if err != nil {
+			return "", err
+		}
+		return FlattenRichText(content), nil
+	}
+
+	if !msgEvent.IsMention || botOpenID == "" {
+		return FlattenRichText(richText), nil
+	}
+
+	content := make(protocol.RichTextContent, 0, len(*richText))
+	stripped := false
+	for _, elements := range *richText {
+		line := make([]protocol.RichTextElementForm, 0, len(elements))
+		for _, element := range elements {
+			if !stripped && element.Tag == "at" && element.UserID == botOpenID {
+				stripped = true
+				continue
+			}
+			line = append(line, element)
+		}
+		content = append(content, line)
+	}
+	return FlattenRichText(&content), nil
+}
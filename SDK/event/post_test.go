@@ -0,0 +1,129 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/larksuite/botframework-go/SDK/appconfig"
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+func TestParsePostText(t *testing.T) {
+	text := `<p><at open_id="ou_bot">@bot</at> deploy <b>v1.2</b> to <a href="https://example.com/prod">prod</a></p>` +
+		`<p>notify <at open_id="ou_tom">@Tom</at> &amp; <a href="https://example.com/log"></a></p>` +
+		`<figure><img src="https://example.com/1.png" origin-width="888" origin-height="492"/></figure>`
+
+	content, err := event.ParsePostText(text, []string{"img_1"})
+	if err != nil {
+		t.Fatalf("ParsePostText: err[%v]", err)
+	}
+	if len(*content) != 3 {
+		t.Fatalf("lines: %d", len(*content))
+	}
+
+	first := (*content)[0]
+	if len(first) != 3 || first[0].Tag != "at" || first[0].UserID != "ou_bot" || *first[1].Text != " deploy v1.2 to " ||
+		first[2].Tag != "a" || first[2].Href != "https://example.com/prod" || *first[2].Text != "prod" {
+		data, _ := json.Marshal(first)
+		t.Errorf("first line: %s", data)
+	}
+	image := (*content)[2][0]
+	if image.Tag != "img" || image.ImageKey != "img_1" || image.Width != 888 || image.Height != 492 {
+		t.Errorf("image: %+v", image)
+	}
+
+	expected := `<at open_id="ou_bot">@bot</at> deploy v1.2 to prod` + "\n" +
+		`notify <at open_id="ou_tom">@Tom</at> & https://example.com/log`
+	if flat := event.FlattenRichText(content); flat != expected {
+		t.Errorf("flatten: %s", flat)
+	}
+}
+
+func TestPostCommand(t *testing.T) {
+	appID := "cli_test_post_command"
+	appconfig.Init(appconfig.AppConfig{
+		AppID:       appID,
+		AppType:     protocol.InternalApp,
+		VerifyToken: testVerifyToken,
+		BotOpenID:   "ou_bot",
+	})
+	router := event.NewRouter()
+
+	var received *protocol.BotRecvMsg
+	var args *event.CommandArgs
+	router.BotRecvMsgRegister(appID, protocol.CmdDefault, func(ctx context.Context, msg *protocol.BotRecvMsg) error {
+		received = msg
+		return nil
+	})
+	router.CommandRegister(appID, &event.Command{
+		Name: "deploy",
+		Args: []*event.CommandArg{{Name: "version", Required: true}},
+		Flags: []*event.CommandFlag{
+			{Name: "notify", Type: event.ArgTypeMention},
+		},
+		Handler: func(ctx context.Context, msg *protocol.BotRecvMsg, a *event.CommandArgs) error {
+			received, args = msg, a
+			return nil
+		},
+	})
+
+	newTestPostMsg := func(text, textWithoutAtBot string) []byte {
+		return newTestMsg(appID, testFields{
+			"msg_type":            "post",
+			"is_mention":          true,
+			"title":               "release",
+			"text":                text,
+			"text_without_at_bot": textWithoutAtBot,
+		})
+	}
+
+	ctx := context.Background()
+	text := `<p><at open_id="ou_bot">@bot</at> deploy <a href="https://example.com/v1.2">v1.2</a> --notify <at open_id="ou_tom">@Tom</at></p>`
+
+	// the bot mention is stripped from the text, or by text_without_at_bot
+	for _, textWithoutAtBot := range []string{"", `<p> deploy <a href="https://example.com/v1.2">v1.2</a> --notify <at open_id="ou_tom">@Tom</at></p>`} {
+		received, args = nil, nil
+		err := router.BotRecvMsgHandler(ctx, newTestPostMsg(text, textWithoutAtBot))
+		if err != nil || args == nil || args.String("version") != "v1.2" || args.Mention("notify") != "ou_tom" {
+			t.Errorf("textWithoutAtBot[%s]: args[%+v]err[%v]", textWithoutAtBot, args, err)
+			continue
+		}
+		if received.RichText == nil || received.RichText.Title != "release" || (*received.RichText.Content)[0][0].UserID != "ou_bot" {
+			t.Errorf("rich text: %+v", received.RichText)
+		}
+	}
+
+	// the post without command goes to the default command
+	received = nil
+	router.BotRecvMsgHandler(ctx, newTestPostMsg(`<p><at open_id="ou_bot">@bot</at> hello</p><p>world</p>`, ""))
+	if received == nil || received.TextParam != "hello\nworld" {
+		t.Errorf("default: %+v", received)
+	}
+
+	// the command is separated by any space, eg: the new line of the post
+	for _, msg := range [][]byte{
+		newTestPostMsg(`<p><at open_id="ou_bot">@bot</at> deploy</p><p>v1.2 --notify <at open_id="ou_tom">@Tom</at></p>`, ""),
+		newTestTextMsg(appID, "deploy\tv1.2\n--notify ou_tom"),
+	} {
+		args = nil
+		err := router.BotRecvMsgHandler(ctx, msg)
+		if err != nil || args == nil || args.String("version") != "v1.2" || args.Mention("notify") != "ou_tom" {
+			t.Errorf("space: msg[%s]args[%+v]err[%v]", msg, args, err)
+		}
+	}
+
+	// the mention of the bot is stripped, not the first mention
+	received = nil
+	router.BotRecvMsgHandler(ctx, newTestPostMsg(`<p><at open_id="ou_tom">@Tom</at> <at open_id="ou_bot">@bot</at> hello</p>`, ""))
+	if received == nil || !strings.HasPrefix(received.TextParam, `<at open_id="ou_tom">@Tom</at>`) ||
+		strings.Contains(received.TextParam, "ou_bot") || !strings.HasSuffix(received.TextParam, "hello") {
+		t.Errorf("mention before the bot: %+v", received)
+	}
+}
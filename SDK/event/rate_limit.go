@@ -0,0 +1,465 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+)
+
+const (
+	RateLimitScopeApp    = "app"
+	RateLimitScopeTenant = "tenant"
+	RateLimitScopeChat   = "chat"
+	RateLimitScopeUser   = "user"
+
+	DefaultRateLimitMaxWait       = 3 * time.Second
+	DefaultRateLimitMaxQueued     = 1024
+	DefaultRateLimitReplyInterval = time.Minute
+	DefaultRateLimitReplyText     = "Too many requests, please try again later."
+
+	rateLimitKeyPrefix      = "rate_limit:"
+	rateLimitReplyKeyPrefix = "rate_limit_reply:"
+	rateLimitLockTTL        = time.Second
+	rateLimitLockRetry      = 20
+	rateLimitSweepInterval  = time.Minute
+)
+
+// OverflowPolicy the behavior when the event exceeds the limit
+type OverflowPolicy int
+
+const (
+	OverflowDrop  OverflowPolicy = iota // ack and drop the event
+	OverflowQueue                       // ack the event and dispatch it once the token is available, drop it if it still exceeds the limit after MaxWait
+	OverflowReply                       // drop the event, and reply the throttling message once in ReplyInterval
+)
+
+// RateLimit the token bucket of each key of the scope, eg: scope chat means a bucket per chat
+type RateLimit struct {
+	Scope      string   // RateLimitScopeApp/RateLimitScopeTenant/RateLimitScopeChat/RateLimitScopeUser
+	Rate       float64  // the tokens added per second
+	Burst      int      // the capacity of the bucket
+	EventTypes []string // the event types limited, empty means all event types
+}
+
+type RateLimitOption struct {
+	Limits   []*RateLimit // all limits matched by the event are checked in order
+	Store    RateLimitStore
+	Overflow OverflowPolicy
+
+	MaxWait       time.Duration                                    // OverflowQueue, 0 means DefaultRateLimitMaxWait
+	MaxQueued     int                                              // OverflowQueue, the events waiting for the tokens, 0 means DefaultRateLimitMaxQueued
+	ReplyText     string                                           // OverflowReply, empty means DefaultRateLimitReplyText
+	ReplyInterval time.Duration                                    // OverflowReply, 0 means DefaultRateLimitReplyInterval
+	Reply         func(ctx context.Context, meta *EventMeta) error // OverflowReply, nil means send ReplyText to the chat(or the user of p2p chat)
+
+	queued int32 // the events waiting for the tokens
+}
+
+// RateLimitStore the state of the token buckets
+type RateLimitStore interface {
+	// Take one token from the bucket of the key, return the wait until the next token if no token is available
+	Take(ctx context.Context, key string, rate float64, burst int) (bool, time.Duration, error)
+	// Once return true for the first call of the key in the interval
+	Once(ctx context.Context, key string, interval time.Duration) (bool, error)
+}
+
+type RateLimitManager struct {
+	mapOption map[string]*RateLimitOption // appID => option
+	rwMu      sync.RWMutex
+}
+
+var rateLimiter *RateLimitManager
+
+func init() {
+	rateLimiter = &RateLimitManager{
+		mapOption: make(map[string]*RateLimitOption, 0),
+	}
+}
+
+func (m *RateLimitManager) Set(appID string, option *RateLimitOption) {
+	m.rwMu.Lock()
+	defer m.rwMu.Unlock()
+
+	if option == nil {
+		delete(m.mapOption, appID)
+		return
+	}
+	m.mapOption[appID] = option
+}
+
+func (m *RateLimitManager) Get(appID string) *RateLimitOption {
+	m.rwMu.RLock()
+	defer m.rwMu.RUnlock()
+
+	return m.mapOption[appID]
+}
+
+// Allow check the limits before the event is dispatched(or queued by the async dispatch), return true if the event is allowed.
+// The event exceeding the limit is not dispatched by the caller:
+// OverflowQueue retries the limit by a timer up to MaxWait and calls dispatch once allowed, OverflowReply replies in background.
+// Neither the callback nor the async worker is blocked. The delayed events are held by the drainer of the router.
+func (m *RateLimitManager) Allow(ctx context.Context, appID string, meta *EventMeta, drainer *common.Drainer, dispatch func(ctx context.Context)) bool {
+	option := m.Get(appID)
+	if option == nil {
+		return true
+	}
+
+	index, wait := option.take(ctx, meta, 0)
+	if index < 0 {
+		return true
+	}
+
+	limit := option.Limits[index]
+	common.Logger(ctx).Warnf("rateLimit: exceed limit, scope[%s]rate[%v]burst[%d]overflow[%d]", limit.Scope, limit.Rate, limit.Burst, option.Overflow)
+	switch option.Overflow {
+	case OverflowQueue:
+		option.delay(common.DetachContext(ctx), meta, index, wait, time.Now(), drainer, dispatch)
+	case OverflowReply:
+		go option.reply(common.DetachContext(ctx), meta, limit)
+	}
+	return false
+}
+
+// take check the limits from the index, return the index of the limit exceeded and the wait until its next token,
+// -1 if the event is allowed. The event is allowed if the store returns error.
+func (option *RateLimitOption) take(ctx context.Context, meta *EventMeta, from int) (int, time.Duration) {
+	for i := from; i < len(option.Limits); i++ {
+		limit := option.Limits[i]
+		key := rateLimitKey(meta, limit)
+		if key == "" {
+			continue
+		}
+
+		allowed, wait, err := option.Store.Take(ctx, key, limit.Rate, limit.Burst)
+		if err != nil {
+			common.Logger(ctx).Warnf("rateLimit: storeError[%v]key[%s]", err, key)
+			continue
+		}
+		if !allowed {
+			return i, wait
+		}
+	}
+	return -1, 0
+}
+
+// delay retry the limit exceeded after wait, the limits passed are not taken again.
+// The event is dropped if it is not allowed in MaxWait, MaxQueued events are waiting, or the router is shutting down.
+func (option *RateLimitOption) delay(ctx context.Context, meta *EventMeta, index int, wait time.Duration, start time.Time,
+	drainer *common.Drainer, dispatch func(ctx context.Context)) {
+	if wait > option.MaxWait {
+		common.Logger(ctx).Warnf("rateLimit: drop event, wait[%v]maxWait[%v]", wait, option.MaxWait)
+		return
+	}
+	if atomic.AddInt32(&option.queued, 1) > int32(option.MaxQueued) {
+		atomic.AddInt32(&option.queued, -1)
+		common.Logger(ctx).Warnf("rateLimit: drop event, queueFull[%d]", option.MaxQueued)
+		return
+	}
+	if !drainer.Acquire() {
+		atomic.AddInt32(&option.queued, -1)
+		common.Logger(ctx).Warnf("rateLimit: drop event, router is shutting down")
+		return
+	}
+
+	done := func() {
+		atomic.AddInt32(&option.queued, -1)
+		drainer.Release()
+	}
+	option.retry(ctx, meta, index, wait, start, done, dispatch)
+}
+
+func (option *RateLimitOption) retry(ctx context.Context, meta *EventMeta, index int, wait time.Duration, start time.Time,
+	done func(), dispatch func(ctx context.Context)) {
+	time.AfterFunc(wait, func() {
+		index, wait := option.take(ctx, meta, index)
+		if index >= 0 && time.Since(start)+wait <= option.MaxWait {
+			option.retry(ctx, meta, index, wait, start, done, dispatch)
+			return
+		}
+
+		defer done()
+		defer common.RecoverPanic(ctx)
+		if index >= 0 {
+			common.Logger(ctx).Warnf("rateLimit: drop event, waitExceed[%v]", option.MaxWait)
+			return
+		}
+		dispatch(ctx)
+	})
+}
+
+func (option *RateLimitOption) reply(ctx context.Context, meta *EventMeta, limit *RateLimit) {
+	defer common.RecoverPanic(ctx)
+
+	// reply once in the interval for the bucket
+	key := rateLimitReplyKeyPrefix + rateLimitKey(meta, limit)
+	first, err := option.Store.Once(ctx, key, option.ReplyInterval)
+	if err != nil {
+		common.Logger(ctx).Warnf("rateLimit: storeError[%v]key[%s]", err, key)
+		return
+	}
+	if !first {
+		return
+	}
+
+	if option.Reply != nil {
+		err = option.Reply(ctx, meta)
+	} else {
+		err = sendThrottlingText(ctx, meta, option.ReplyText)
+	}
+	if err != nil {
+		common.Logger(ctx).Errorf("rateLimit: replyError[%v]", err)
+	}
+}
+
+func sendThrottlingText(ctx context.Context, meta *EventMeta, text string) error {
+	return replyText(ctx, meta.TenantKey, meta.AppID, meta.ChatID, meta.OpenID, meta.MessageID, text)
+}
+
+// rateLimitKey return empty if the event doesn't match the limit
+func rateLimitKey(meta *EventMeta, limit *RateLimit) string {
+	if len(limit.EventTypes) != 0 && !contains(limit.EventTypes, meta.EventType) {
+		return ""
+	}
+
+	var value string
+	switch limit.Scope {
+	case RateLimitScopeApp:
+		value = meta.AppID
+	case RateLimitScopeTenant:
+		value = meta.TenantKey
+	case RateLimitScopeChat:
+		value = meta.ChatID
+	case RateLimitScopeUser:
+		value = meta.OpenID
+	}
+	if value == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s%s:%s:%s", rateLimitKeyPrefix, meta.AppID, limit.Scope, value)
+}
+
+// SetRateLimit demo:
+// event.SetRateLimit(appID, &event.RateLimitOption{
+// 	Limits: []*event.RateLimit{
+// 		{Scope: event.RateLimitScopeChat, Rate: 1, Burst: 5, EventTypes: []string{protocol.EventTypeMessage}},
+// 		{Scope: event.RateLimitScopeTenant, Rate: 20, Burst: 50},
+// 	},
+// 	Overflow: event.OverflowReply,
+// })
+//
+// The limits are checked before the event is dispatched, the event exceeding the limit is not queued by the async dispatch.
+// Store nil means the in-memory store, use NewDBRateLimitStore to share the limits across replicas. A nil option removes the limits.
+func SetRateLimit(appID string, option *RateLimitOption) error {
+	if appID == "" {
+		return common.ErrEventRateLimitParams.ErrorWithExtStr("rate limit appID is empty")
+	}
+	if option == nil {
+		rateLimiter.Set(appID, nil)
+		return nil
+	}
+
+	for _, limit := range option.Limits {
+		if limit == nil || limit.Rate <= 0 || limit.Burst <= 0 {
+			return common.ErrEventRateLimitParams.ErrorWithExtStr(fmt.Sprintf("rate limit invalid, limit[%+v]", limit))
+		}
+		switch limit.Scope {
+		case RateLimitScopeApp, RateLimitScopeTenant, RateLimitScopeChat, RateLimitScopeUser:
+		default:
+			return common.ErrEventRateLimitParams.ErrorWithExtStr(fmt.Sprintf("rate limit scope[%s] invalid", limit.Scope))
+		}
+	}
+
+	opt := *option
+	if opt.Store == nil {
+		opt.Store = NewMemoryRateLimitStore()
+	}
+	opt.queued = 0
+	if opt.MaxQueued <= 0 {
+		opt.MaxQueued = DefaultRateLimitMaxQueued
+	}
+	if opt.MaxWait <= 0 {
+		opt.MaxWait = DefaultRateLimitMaxWait
+	}
+	if opt.ReplyText == "" {
+		opt.ReplyText = DefaultRateLimitReplyText
+	}
+	if opt.ReplyInterval <= 0 {
+		opt.ReplyInterval = DefaultRateLimitReplyInterval
+	}
+
+	rateLimiter.Set(appID, &opt)
+	return nil
+}
+
+type tokenBucket struct {
+	Tokens float64 `json:"tokens"`
+	Last   int64   `json:"last"` // unix nano of the last refill
+}
+
+// take refill the bucket and take one token
+func (b *tokenBucket) take(now time.Time, rate float64, burst int) (bool, time.Duration) {
+	if b.Last == 0 {
+		b.Tokens = float64(burst)
+	} else if elapsed := now.UnixNano() - b.Last; elapsed > 0 {
+		b.Tokens = math.Min(float64(burst), b.Tokens+float64(elapsed)/float64(time.Second)*rate)
+	}
+	b.Last = now.UnixNano()
+
+	if b.Tokens >= 1 {
+		b.Tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.Tokens) / rate * float64(time.Second))
+}
+
+// idle the duration after which the bucket is full again
+func (b *tokenBucket) idle(rate float64, burst int) time.Duration {
+	return time.Duration((float64(burst) - b.Tokens) / rate * float64(time.Second))
+}
+
+// MemoryRateLimitStore the buckets of one process.
+// The buckets full again and the expired Once keys are swept at most once a minute, so the idle keys don't grow the maps.
+type MemoryRateLimitStore struct {
+	buckets   map[string]*memoryBucket
+	once      map[string]time.Time // key => expire time
+	nextSweep time.Time
+	mu        sync.Mutex
+}
+
+type memoryBucket struct {
+	tokenBucket
+	full time.Time // the bucket is full again, the same as a new bucket
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets:   make(map[string]*memoryBucket, 0),
+		once:      make(map[string]time.Time, 0),
+		nextSweep: time.Now().Add(rateLimitSweepInterval),
+	}
+}
+
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, rate float64, burst int) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{}
+		s.buckets[key] = bucket
+	}
+
+	allowed, wait := bucket.take(now, rate, burst)
+	bucket.full = now.Add(bucket.idle(rate, burst))
+	return allowed, wait, nil
+}
+
+func (s *MemoryRateLimitStore) Once(ctx context.Context, key string, interval time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	if expire, ok := s.once[key]; ok && now.Before(expire) {
+		return false, nil
+	}
+	s.once[key] = now.Add(interval)
+	return true, nil
+}
+
+// sweep remove the full buckets and the expired Once keys, called with the lock held
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Before(s.nextSweep) {
+		return
+	}
+	s.nextSweep = now.Add(rateLimitSweepInterval)
+
+	for key, bucket := range s.buckets {
+		if !now.Before(bucket.full) {
+			delete(s.buckets, key)
+		}
+	}
+	for key, expire := range s.once {
+		if !now.Before(expire) {
+			delete(s.once, key)
+		}
+	}
+}
+
+// DBRateLimitStore the buckets shared across replicas by common.DBClient.
+// The bucket is locked by common.TryLock while updating if the client implements common.DBClientEx, otherwise the update is not atomic.
+type DBRateLimitStore struct {
+	client common.DBClient
+}
+
+func NewDBRateLimitStore(client common.DBClient) (*DBRateLimitStore, error) {
+	if client == nil {
+		return nil, common.ErrEventRateLimitParams.ErrorWithExtStr("rate limit db client is nil")
+	}
+
+	return &DBRateLimitStore{client: client}, nil
+}
+
+func (s *DBRateLimitStore) Take(ctx context.Context, key string, rate float64, burst int) (bool, time.Duration, error) {
+	unlock, err := s.lock(key)
+	if err != nil {
+		return false, 0, err
+	}
+	defer unlock()
+
+	bucket := &tokenBucket{}
+	if value, err := s.client.Get(key); err == nil && value != "" {
+		json.Unmarshal([]byte(value), bucket)
+	}
+
+	allowed, wait := bucket.take(time.Now(), rate, burst)
+
+	value, err := json.Marshal(bucket)
+	if err != nil {
+		return false, 0, common.ErrJsonMarshal.ErrorWithExtErr(err)
+	}
+	// the bucket is full after idle, it can expire
+	err = s.client.Set(key, string(value), bucket.idle(rate, burst)+time.Second)
+	if err != nil {
+		return false, 0, err
+	}
+
+	return allowed, wait, nil
+}
+
+func (s *DBRateLimitStore) Once(ctx context.Context, key string, interval time.Duration) (bool, error) {
+	return common.SetIfAbsent(s.client, key, "1", interval)
+}
+
+func (s *DBRateLimitStore) lock(key string) (func(), error) {
+	if _, ok := s.client.(common.DBClientEx); !ok {
+		return func() {}, nil
+	}
+
+	lockKey := key + ":lock"
+	for i := 0; i < rateLimitLockRetry; i++ {
+		unlock, err := common.TryLock(s.client, lockKey, rateLimitLockTTL)
+		if err != nil {
+			return nil, err
+		}
+		if unlock != nil {
+			return unlock, nil
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return nil, fmt.Errorf("lock key[%s] timeout", lockKey)
+}
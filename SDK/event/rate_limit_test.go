@@ -0,0 +1,209 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event_test
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+func registerCountHandler(router *event.Router, appID string) *int32 {
+	var called int32
+	router.EventRegister(appID, protocol.EventTypeMessage, func(ctx context.Context, eventBody []byte) error {
+		atomic.AddInt32(&called, 1)
+		return nil
+	})
+	return &called
+}
+
+func TestRateLimitDrop(t *testing.T) {
+	appID := "cli_test_rate_limit_drop"
+	initTestApp(appID)
+
+	router := event.NewRouter()
+	called := registerCountHandler(router, appID)
+
+	err := event.SetRateLimit(appID, &event.RateLimitOption{
+		Limits: []*event.RateLimit{{Scope: event.RateLimitScopeChat, Rate: 0.1, Burst: 2}},
+	})
+	if err != nil {
+		t.Fatalf("SetRateLimit: err[%v]", err)
+	}
+	defer event.SetRateLimit(appID, nil)
+
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		_, err = router.EventCallback(ctx, newTestEventBody(appID, fmt.Sprintf("drop_%d", i), protocol.EventTypeMessage, testFields{"open_chat_id": "oc_noisy"}), appID)
+		if err != nil {
+			t.Errorf("event[%d]: err[%v]", i, err)
+		}
+	}
+	if n := atomic.LoadInt32(called); n != 2 {
+		t.Errorf("noisy chat: called[%d]", n)
+	}
+
+	// the other chat has its own bucket
+	router.EventCallback(ctx, newTestEventBody(appID, "drop_other", protocol.EventTypeMessage, testFields{"open_chat_id": "oc_other"}), appID)
+	if n := atomic.LoadInt32(called); n != 3 {
+		t.Errorf("other chat: called[%d]", n)
+	}
+}
+
+func waitCalled(called *int32, expected int32, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if atomic.LoadInt32(called) == expected {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return atomic.LoadInt32(called) == expected
+}
+
+func TestRateLimitQueue(t *testing.T) {
+	appID := "cli_test_rate_limit_queue"
+	initTestApp(appID)
+
+	router := event.NewRouter()
+	called := registerCountHandler(router, appID)
+
+	event.SetRateLimit(appID, &event.RateLimitOption{
+		Limits:   []*event.RateLimit{{Scope: event.RateLimitScopeApp, Rate: 20, Burst: 1}},
+		Overflow: event.OverflowQueue,
+		MaxWait:  time.Second,
+	})
+	defer event.SetRateLimit(appID, nil)
+
+	// the callbacks are not blocked by the limit, the events are dispatched once the tokens are available
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		router.EventCallback(ctx, newTestEventBody(appID, fmt.Sprintf("queue_%d", i), protocol.EventTypeMessage), appID)
+	}
+	if cost := time.Since(start); cost > 40*time.Millisecond {
+		t.Errorf("queue: callback blocked, cost[%v]", cost)
+	}
+	if !waitCalled(called, 3, time.Second) {
+		t.Fatalf("queue: called[%d]", atomic.LoadInt32(called))
+	}
+	if cost := time.Since(start); cost < 80*time.Millisecond {
+		t.Errorf("queue: not waiting for the tokens, cost[%v]", cost)
+	}
+
+	// the router waits for the delayed events when shutting down
+	router.EventCallback(ctx, newTestEventBody(appID, "queue_shutdown_0", protocol.EventTypeMessage), appID)
+	router.EventCallback(ctx, newTestEventBody(appID, "queue_shutdown_1", protocol.EventTypeMessage), appID)
+	if err := router.Shutdown(ctx); err != nil || atomic.LoadInt32(called) != 5 {
+		t.Errorf("shutdown: called[%d]err[%v]", atomic.LoadInt32(called), err)
+	}
+}
+
+func TestRateLimitQueueFull(t *testing.T) {
+	appID := "cli_test_rate_limit_queue_full"
+	initTestApp(appID)
+
+	router := event.NewRouter()
+	called := registerCountHandler(router, appID)
+
+	event.SetRateLimit(appID, &event.RateLimitOption{
+		Limits:    []*event.RateLimit{{Scope: event.RateLimitScopeApp, Rate: 20, Burst: 1}},
+		Overflow:  event.OverflowQueue,
+		MaxQueued: 1,
+	})
+	defer event.SetRateLimit(appID, nil)
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		router.EventCallback(ctx, newTestEventBody(appID, fmt.Sprintf("queue_full_%d", i), protocol.EventTypeMessage), appID)
+	}
+	router.Shutdown(ctx)
+	if n := atomic.LoadInt32(called); n != 2 {
+		t.Errorf("queue full: called[%d]", n)
+	}
+}
+
+func TestRateLimitReplyOnce(t *testing.T) {
+	appID := "cli_test_rate_limit_reply"
+	initTestApp(appID)
+
+	router := event.NewRouter()
+	called := registerCountHandler(router, appID)
+
+	var replied int32
+	event.SetRateLimit(appID, &event.RateLimitOption{
+		Limits:   []*event.RateLimit{{Scope: event.RateLimitScopeUser, Rate: 0.1, Burst: 1}},
+		Overflow: event.OverflowReply,
+		Reply: func(ctx context.Context, meta *event.EventMeta) error {
+			if meta.OpenID != "ou_1" {
+				t.Errorf("reply: unexpected meta[%+v]", meta)
+			}
+			atomic.AddInt32(&replied, 1)
+			return nil
+		},
+	})
+	defer event.SetRateLimit(appID, nil)
+
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		router.EventCallback(ctx, newTestEventBody(appID, fmt.Sprintf("reply_%d", i), protocol.EventTypeMessage), appID)
+	}
+	// the reply is sent in background
+	if !waitCalled(&replied, 1, time.Second) || atomic.LoadInt32(called) != 1 {
+		t.Errorf("reply: called[%d]replied[%d]", atomic.LoadInt32(called), atomic.LoadInt32(&replied))
+	}
+}
+
+func TestDBRateLimitStore(t *testing.T) {
+	db := &common.MemoryDBClient{}
+	db.InitDB(nil)
+	store, err := event.NewDBRateLimitStore(db)
+	if err != nil {
+		t.Fatalf("NewDBRateLimitStore: err[%v]", err)
+	}
+
+	ctx := context.Background()
+	for i, expected := range []bool{true, true, false} {
+		allowed, wait, err := store.Take(ctx, "bucket", 10, 2)
+		if err != nil || allowed != expected {
+			t.Errorf("take[%d]: allowed[%t]err[%v]", i, allowed, err)
+		}
+		if !allowed && (wait <= 0 || wait > 100*time.Millisecond) {
+			t.Errorf("take[%d]: wait[%v]", i, wait)
+		}
+	}
+
+	// refilled
+	time.Sleep(120 * time.Millisecond)
+	if allowed, _, _ := store.Take(ctx, "bucket", 10, 2); !allowed {
+		t.Errorf("take after refill: not allowed")
+	}
+
+	first, _ := store.Once(ctx, "reply", time.Minute)
+	again, _ := store.Once(ctx, "reply", time.Minute)
+	if !first || again {
+		t.Errorf("once: first[%t]again[%t]", first, again)
+	}
+}
+
+func TestSetRateLimitParams(t *testing.T) {
+	cases := []*event.RateLimit{
+		{Scope: event.RateLimitScopeApp, Rate: 0, Burst: 1},
+		{Scope: event.RateLimitScopeApp, Rate: 1, Burst: 0},
+		{Scope: "group", Rate: 1, Burst: 1},
+	}
+	for i, limit := range cases {
+		err := event.SetRateLimit("cli_test_rate_limit_params", &event.RateLimitOption{Limits: []*event.RateLimit{limit}})
+		if err == nil {
+			t.Errorf("case[%d]: err is nil", i)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+)
+
+const (
+	RecordKindEvent = "event"
+	RecordKindCard  = "card"
+
+	// HeaderReplay sent by replay.HTTPTarget, only checked by the server trusting it, see webhook.Option.TrustReplayHeader
+	HeaderReplay        = "X-Botframework-Replay"
+	ReplayValue         = "1"
+	ReplayValueRedacted = "redacted" // the record is redacted, see Record.Redacted
+
+	redactedValue = "***"
+
+	replayContextKey = "botframework_replay"
+)
+
+// Record the raw callback received by EventCallback/CardCallBack
+type Record struct {
+	Kind      string            `json:"kind"` // RecordKindEvent or RecordKindCard
+	AppID     string            `json:"app_id"`
+	EventType string            `json:"event_type,omitempty"` // event type of the event callback, empty if the body can't be parsed
+	Method    string            `json:"method,omitempty"`     // method of the card callback
+	Header    map[string]string `json:"header,omitempty"`
+	Body      string            `json:"body"`
+	Time      time.Time         `json:"time"`
+	Cost      time.Duration     `json:"cost"`
+	Error     string            `json:"error,omitempty"`
+	Redacted  bool              `json:"redacted,omitempty"` // the body or the header is changed by the redactors, the signature can't be verified
+}
+
+// Recorder the hook called after each callback is handled, see SetRecorder
+type Recorder interface {
+	Record(ctx context.Context, record *Record) error
+}
+
+// Redactor modify the record before it is written, eg: remove the sensitive fields
+type Redactor func(record *Record)
+
+var (
+	callbackRecorder Recorder
+	recorderRWMu     sync.RWMutex
+)
+
+// SetRecorder demo:
+// recorder, err := event.NewFileRecorder("./callback.jsonl", event.RedactJsonFields("text", "text_without_at_bot"))
+// event.SetRecorder(recorder)
+// defer recorder.Close()
+//
+// A nil recorder disables the recording.
+func SetRecorder(recorder Recorder) {
+	recorderRWMu.Lock()
+	defer recorderRWMu.Unlock()
+
+	callbackRecorder = recorder
+}
+
+func getRecorder() Recorder {
+	recorderRWMu.RLock()
+	defer recorderRWMu.RUnlock()
+
+	return callbackRecorder
+}
+
+type replayMode struct {
+	redacted bool
+}
+
+// WithReplay mark the callbacks as replayed, eg: by the package replay.
+// The guards against the duplicate or stale delivery are bypassed: the event dedup, the card replay protection
+// and the timestamp window of the event signature. The signature itself is still verified,
+// unless redacted is true(see Record.Redacted): the signature of the redacted record can't be verified.
+func WithReplay(ctx context.Context, redacted bool) context.Context {
+	return context.WithValue(ctx, replayContextKey, replayMode{redacted: redacted})
+}
+
+// IsReplay the callback is replayed, see WithReplay
+func IsReplay(ctx context.Context) bool {
+	_, ok := ctx.Value(replayContextKey).(replayMode)
+	return ok
+}
+
+// isRedactedReplay the redacted record is replayed, the signature is not checked
+func isRedactedReplay(ctx context.Context) bool {
+	mode, ok := ctx.Value(replayContextKey).(replayMode)
+	return ok && mode.redacted
+}
+
+// newRecord return nil if the recording is disabled
+func newRecord(kind, appID string, header map[string]string, body string) *Record {
+	if getRecorder() == nil {
+		return nil
+	}
+
+	record := &Record{
+		Kind:  kind,
+		AppID: appID,
+		Body:  body,
+		Time:  time.Now(),
+	}
+	if len(header) != 0 {
+		record.Header = make(map[string]string, len(header))
+		for k, v := range header {
+			record.Header[k] = v
+		}
+	}
+	return record
+}
+
+func (record *Record) finish(ctx context.Context, err error) {
+	if record == nil {
+		return
+	}
+
+	recorder := getRecorder()
+	if recorder == nil {
+		return
+	}
+
+	record.Cost = time.Since(record.Time)
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	if err := recorder.Record(ctx, record); err != nil {
+		common.Logger(ctx).Warnf("callbackRecorder: recordError[%v]kind[%s]appid[%s]", err, record.Kind, record.AppID)
+	}
+}
+
+// FileRecorder one record per line(json lines)
+type FileRecorder struct {
+	file      *os.File
+	redactors []Redactor
+	mu        sync.Mutex
+}
+
+func NewFileRecorder(path string, redactors ...Redactor) (*FileRecorder, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileRecorder{
+		file:      f,
+		redactors: redactors,
+	}, nil
+}
+
+func (f *FileRecorder) Record(ctx context.Context, record *Record) error {
+	for _, redact := range f.redactors {
+		if redact != nil {
+			redact(record)
+		}
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return common.ErrJsonMarshal.ErrorWithExtErr(err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, err = f.file.Write(append(line, '\n'))
+	return err
+}
+
+func (f *FileRecorder) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.file.Close()
+}
+
+// RedactHeader replace the values of the headers, the record is marked Redacted if any header is replaced
+func RedactHeader(keys ...string) Redactor {
+	return func(record *Record) {
+		for _, key := range keys {
+			if _, ok := record.Header[key]; ok {
+				record.Header[key] = redactedValue
+				record.Redacted = true
+			}
+		}
+	}
+}
+
+// RedactJsonFields replace the values of the fields at any level of the json body.
+// The record is marked Redacted if any value is replaced, its signature can't be verified anymore,
+// and it's replayed without the signature check(see WithReplay). The body without the fields is not changed.
+//
+// NOTE: the encrypted body is not decrypted, the fields in it are NOT redacted.
+func RedactJsonFields(fields ...string) Redactor {
+	set := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		set[field] = true
+	}
+
+	return func(record *Record) {
+		body, ok := redactJson([]byte(record.Body), set)
+		if ok {
+			record.Body = string(body)
+			record.Redacted = true
+		}
+	}
+}
+
+// redactJson return false if the body is invalid or has none of the fields
+func redactJson(body []byte, fields map[string]bool) ([]byte, bool) {
+	type span struct{ start, end int }
+	spans := make([]span, 0)
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	objects := make([]bool, 0) // the containers, true for the object and false for the array
+	isKey := false
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false
+		}
+
+		switch token {
+		case json.Delim('{'):
+			objects = append(objects, true)
+			isKey = true
+			continue
+		case json.Delim('['):
+			objects = append(objects, false)
+			isKey = false
+			continue
+		case json.Delim('}'), json.Delim(']'):
+			objects = objects[:len(objects)-1]
+		default:
+			if key, ok := token.(string); ok && isKey {
+				isKey = false
+				if !fields[key] {
+					continue
+				}
+
+				// skip the colon, then the value
+				start := int(dec.InputOffset())
+				for start < len(body) && bytes.IndexByte([]byte(": \t\r\n"), body[start]) >= 0 {
+					start++
+				}
+				if skipJsonValue(dec) != nil {
+					return nil, false
+				}
+				spans = append(spans, span{start: start, end: int(dec.InputOffset())})
+			}
+		}
+		// the value is done, the key is next in the object
+		isKey = len(objects) != 0 && objects[len(objects)-1]
+	}
+	if len(spans) == 0 {
+		return nil, false
+	}
+
+	redacted := make([]byte, 0, len(body))
+	last := 0
+	for _, s := range spans {
+		redacted = append(redacted, body[last:s.start]...)
+		redacted = append(redacted, `"`+redactedValue+`"`...)
+		last = s.end
+	}
+	return append(redacted, body[last:]...), true
+}
+
+func skipJsonValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+func TestFileRecorder(t *testing.T) {
+	appID := "cli_test_recorder"
+	initTestApp(appID)
+	event.EventRegister(appID, protocol.EventTypeAddBot, func(ctx context.Context, eventBody []byte) error {
+		return nil
+	})
+
+	path := filepath.Join(t.TempDir(), "callback.jsonl")
+	recorder, err := event.NewFileRecorder(path, event.RedactJsonFields("tenant_key"), event.RedactHeader(event.HeaderSignature))
+	if err != nil {
+		t.Fatalf("new recorder: err[%v]", err)
+	}
+	event.SetRecorder(recorder)
+	defer event.SetRecorder(nil)
+
+	ctx := context.Background()
+	header := map[string]string{event.HeaderSignature: "sig"}
+	event.EventCallbackWithHeader(ctx, newTestEventBody(appID, "recorder_1", protocol.EventTypeAddBot), appID, header)
+	event.EventCallbackWithHeader(ctx, newTestEventBody(appID, "recorder_2", protocol.EventTypeRemoveBot), appID, header)
+	recorder.Close()
+
+	data, _ := ioutil.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("records: lines[%d]", len(lines))
+	}
+
+	record := &event.Record{}
+	json.Unmarshal([]byte(lines[0]), record)
+	if record.Kind != event.RecordKindEvent || record.AppID != appID || record.EventType != protocol.EventTypeAddBot || record.Error != "" {
+		t.Errorf("record: %+v", record)
+	}
+	if strings.Contains(record.Body, `"tenant"`) || record.Header[event.HeaderSignature] != "***" || !record.Redacted {
+		t.Errorf("redact: body[%s]header[%v]", record.Body, record.Header)
+	}
+
+	// the failed callback is recorded with error
+	json.Unmarshal([]byte(lines[1]), record)
+	if record.EventType != protocol.EventTypeRemoveBot || record.Error == "" {
+		t.Errorf("failed record: %+v", record)
+	}
+}
+
+func TestRedactJsonFields(t *testing.T) {
+	redact := event.RedactJsonFields("text", "user")
+
+	// only the values are replaced, the other bytes are kept
+	record := &event.Record{Body: `{"z":1, "text" : "hi","event":{"user":{"name":"tom"},"list":[{"text":"a"}, 1.50]}}`}
+	redact(record)
+	if record.Body != `{"z":1, "text" : "***","event":{"user":"***","list":[{"text":"***"}, 1.50]}}` || !record.Redacted {
+		t.Errorf("redact: body[%s]redacted[%t]", record.Body, record.Redacted)
+	}
+
+	// the body without the fields is not changed, the encrypted body is not redacted
+	for _, body := range []string{`{"b":"é", "a":1e2}`, `{"encrypt":"xxx"}`, `invalid`} {
+		record = &event.Record{Body: body}
+		redact(record)
+		if record.Body != body || record.Redacted {
+			t.Errorf("unchanged: body[%s]expected[%s]redacted[%t]", record.Body, body, record.Redacted)
+		}
+	}
+}
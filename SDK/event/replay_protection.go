@@ -0,0 +1,116 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+)
+
+const (
+	DefaultNonceTTL = 10 * time.Minute
+
+	nonceKeyPrefix = "card_nonce:"
+)
+
+type ReplayOption struct {
+	MaxSkew    time.Duration   // reject the request whose X-Lark-Request-Timestamp is outside the skew, 0 means no check
+	NonceCache common.DBClient // reject the request whose X-Lark-Request-Nonce has been used, nil means no check
+	NonceTTL   time.Duration   // the nonce is kept for NonceTTL, it should be longer than MaxSkew
+}
+
+type ReplayManager struct {
+	mapOption map[string]*ReplayOption // appID => option
+	rwMu      sync.RWMutex
+}
+
+func (r *ReplayManager) Set(appID string, option *ReplayOption) {
+	r.rwMu.Lock()
+	defer r.rwMu.Unlock()
+
+	if option == nil {
+		delete(r.mapOption, appID)
+		return
+	}
+
+	r.mapOption[appID] = option
+}
+
+func (r *ReplayManager) Get(appID string) *ReplayOption {
+	r.rwMu.RLock()
+	defer r.rwMu.RUnlock()
+
+	return r.mapOption[appID]
+}
+
+var cardReplay *ReplayManager
+
+func init() {
+	cardReplay = &ReplayManager{
+		mapOption: make(map[string]*ReplayOption, 0),
+	}
+}
+
+// SetCardReplayProtection demo:
+// event.SetCardReplayProtection(appID, &event.ReplayOption{
+// 	MaxSkew:    5 * time.Minute,
+// 	NonceCache: redisClient,
+// 	NonceTTL:   10 * time.Minute,
+// })
+//
+// A nil option disables the protection.
+func SetCardReplayProtection(appID string, option *ReplayOption) {
+	if option != nil && option.NonceTTL <= 0 {
+		op := *option
+		op.NonceTTL = DefaultNonceTTL
+		if op.MaxSkew*2 > op.NonceTTL {
+			op.NonceTTL = op.MaxSkew * 2
+		}
+		option = &op
+	}
+
+	cardReplay.Set(appID, option)
+}
+
+// checkCardReplay call it after the signature is verified, the replayed callback is not checked, see WithReplay
+func checkCardReplay(ctx context.Context, appID string, header map[string]string) error {
+	option := cardReplay.Get(appID)
+	if option == nil || IsReplay(ctx) {
+		return nil
+	}
+
+	timestamp := header[HeaderRequestTimestamp]
+	nonce := header[HeaderRequestNonce]
+
+	if option.MaxSkew > 0 {
+		err := checkTimestamp(timestamp, option.MaxSkew)
+		if err != nil {
+			return common.ErrCardTimestampExpired.ErrorWithExtErr(err)
+		}
+	}
+
+	if option.NonceCache != nil {
+		if nonce == "" {
+			return common.ErrCardRequestReplayed.ErrorWithExtStr("nonce is empty")
+		}
+
+		key := fmt.Sprintf("%s%s:%s", nonceKeyPrefix, appID, nonce)
+		ok, err := common.SetIfAbsent(option.NonceCache, key, timestamp, option.NonceTTL)
+		if err != nil {
+			// the request is not rejected when the cache is unavailable
+			common.Logger(ctx).Warnf("cardReplay: nonceCacheError[%v]appid[%s]", err, appID)
+			return nil
+		}
+		if !ok {
+			return common.ErrCardRequestReplayed.ErrorWithExtStr(fmt.Sprintf("nonce[%s]timestamp[%s]", nonce, timestamp))
+		}
+	}
+
+	return nil
+}
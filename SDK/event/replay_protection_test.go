@@ -0,0 +1,86 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event_test
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+func TestCardReplayProtection(t *testing.T) {
+	appID := "cli_test_card_replay"
+	initTestApp(appID)
+
+	called := 0
+	event.CardRegister(appID, "replay_click", func(ctx context.Context, cardCallback *protocol.CardCallbackForm) (*protocol.CardForm, error) {
+		called++
+		return &protocol.CardForm{}, nil
+	})
+	event.SetCardReplayProtection(appID, &event.ReplayOption{
+		MaxSkew:    time.Minute,
+		NonceCache: common.NewMemoryDBClient(),
+	})
+	defer event.SetCardReplayProtection(appID, nil)
+
+	ctx := context.Background()
+	body := []byte(`{"open_id":"ou_123","action":{"value":{"method":"replay_click","sid":"1"}}}`)
+	signHeader := func(timestamp int64, nonce string) map[string]string {
+		ts := strconv.FormatInt(timestamp, 10)
+		return map[string]string{
+			event.HeaderRequestTimestamp: ts,
+			event.HeaderRequestNonce:     nonce,
+			event.HeaderSignature:        fmt.Sprintf("%x", sha1.Sum([]byte(ts+nonce+testVerifyToken+string(body)))),
+		}
+	}
+
+	// valid
+	header := signHeader(time.Now().Unix(), "nonce_1")
+	_, _, err := event.CardCallBack(ctx, appID, header, body)
+	if err != nil || called != 1 {
+		t.Errorf("valid: called[%d]err[%v]", called, err)
+	}
+
+	// replayed
+	_, _, err = event.CardCallBack(ctx, appID, header, body)
+	if err == nil || !strings.Contains(err.Error(), common.ErrCardRequestReplayed.String()) {
+		t.Errorf("replayed: err[%v]", err)
+	}
+
+	// stale timestamp
+	_, _, err = event.CardCallBack(ctx, appID, signHeader(time.Now().Add(-time.Hour).Unix(), "nonce_2"), body)
+	if err == nil || !strings.Contains(err.Error(), common.ErrCardTimestampExpired.String()) {
+		t.Errorf("stale timestamp: err[%v]", err)
+	}
+
+	// new nonce
+	_, _, err = event.CardCallBack(ctx, appID, signHeader(time.Now().Unix(), "nonce_3"), body)
+	if err != nil || called != 2 {
+		t.Errorf("new nonce: called[%d]err[%v]", called, err)
+	}
+
+	// the replayed callback is not checked, the signature is checked unless the record is redacted
+	_, _, err = event.CardCallBack(event.WithReplay(ctx, false), appID, header, body)
+	if err != nil || called != 3 {
+		t.Errorf("replay: called[%d]err[%v]", called, err)
+	}
+	redacted := []byte(`{"open_id":"***","action":{"value":{"method":"replay_click","sid":"1"}}}`)
+	_, _, err = event.CardCallBack(event.WithReplay(ctx, false), appID, header, redacted)
+	if err == nil || !strings.Contains(err.Error(), common.ErrCardSignatureInvalid.String()) {
+		t.Errorf("replay redacted without mark: err[%v]", err)
+	}
+	_, _, err = event.CardCallBack(event.WithReplay(ctx, true), appID, header, redacted)
+	if err != nil || called != 4 {
+		t.Errorf("replay redacted: called[%d]err[%v]", called, err)
+	}
+}
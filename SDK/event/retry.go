@@ -0,0 +1,185 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+const (
+	DefaultRetryMaxAttempts    = 3
+	DefaultRetryInitialBackoff = 100 * time.Millisecond
+	DefaultRetryMaxBackoff     = 5 * time.Second
+	DefaultRetryMultiplier     = 2.0
+)
+
+type RetryPolicy struct {
+	MaxAttempts    int           // the number of calls including the first one, 1 means no retry
+	InitialBackoff time.Duration // the wait before the first retry
+	MaxBackoff     time.Duration // the upper limit of the wait
+	Multiplier     float64       // the wait is multiplied after each retry, less than 1 means a constant wait
+	RetryTimeout   bool          // retry the timed out handler(see SetEventTimeout) after it returns, default false
+}
+
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    DefaultRetryMaxAttempts,
+		InitialBackoff: DefaultRetryInitialBackoff,
+		MaxBackoff:     DefaultRetryMaxBackoff,
+		Multiplier:     DefaultRetryMultiplier,
+	}
+}
+
+// Backoff the wait before the retry-th retry, retry starts from 1
+func (p *RetryPolicy) Backoff(retry int) time.Duration {
+	backoff := float64(p.InitialBackoff)
+	for i := 1; i < retry && p.Multiplier > 1; i++ {
+		backoff *= p.Multiplier
+	}
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		return p.MaxBackoff
+	}
+	return time.Duration(backoff)
+}
+
+type RetryManager struct {
+	mapPolicy map[string]*RetryPolicy   // appID => policy
+	mapSink   map[string]DeadLetterSink // appID => sink
+	rwMu      sync.RWMutex
+}
+
+func (m *RetryManager) SetPolicy(appID string, policy *RetryPolicy) {
+	m.rwMu.Lock()
+	defer m.rwMu.Unlock()
+
+	if policy == nil {
+		delete(m.mapPolicy, appID)
+		return
+	}
+	m.mapPolicy[appID] = policy
+}
+
+func (m *RetryManager) SetSink(appID string, sink DeadLetterSink) {
+	m.rwMu.Lock()
+	defer m.rwMu.Unlock()
+
+	if sink == nil {
+		delete(m.mapSink, appID)
+		return
+	}
+	m.mapSink[appID] = sink
+}
+
+func (m *RetryManager) Get(appID string) (*RetryPolicy, DeadLetterSink) {
+	m.rwMu.RLock()
+	defer m.rwMu.RUnlock()
+
+	return m.mapPolicy[appID], m.mapSink[appID]
+}
+
+// Wrap retry the failed handler by the policy of the app, and put the event into the dead letter sink after the retries run out.
+// The event is acknowledged if it is put into the sink successfully.
+// The retry of the fanned-out handlers skips the subscribers succeeded in the previous attempts, see FanOutPolicy.
+func (m *RetryManager) Wrap(appID string, header *protocol.EventHeader, handler EventHandler) EventHandler {
+	policy, sink := m.Get(appID)
+	if policy == nil && sink == nil {
+		return handler
+	}
+
+	return func(ctx context.Context, eventBody []byte) error {
+		ctx = withFanOutProgress(ctx)
+
+		attempts := 1
+		err := handler(ctx, eventBody)
+		for policy != nil && err != nil && attempts < policy.MaxAttempts {
+			if isTimeout(err) && !policy.RetryTimeout {
+				break
+			}
+
+			backoff := policy.Backoff(attempts)
+			common.Logger(ctx).Warnf("eventRetry: handlerError[%v]appid[%s]eventType[%s]attempts[%d]backoff[%v]",
+				err, appID, header.EventType, attempts, backoff)
+
+			// the attempts don't overlap, the timed out handler still running in background is waited for
+			if waitErr := waitTimedOut(ctx, err); waitErr != nil {
+				return fmt.Errorf("retry canceled[%v], last error[%v]", waitErr, err)
+			}
+
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return fmt.Errorf("retry canceled[%v], last error[%v]", ctx.Err(), err)
+			}
+
+			attempts++
+			err = handler(ctx, eventBody)
+		}
+
+		// the redriven letter is kept in the sink by RedriveDeadLetters
+		if err == nil || sink == nil || isRedrive(ctx) {
+			return err
+		}
+
+		letter := newDeadLetter(header, eventBody, err, attempts)
+		sinkErr := sink.Put(ctx, letter)
+		if sinkErr != nil {
+			common.Logger(ctx).Errorf("eventRetry: putDeadLetterError[%v]appid[%s]eventType[%s]eventID[%s]",
+				sinkErr, appID, header.EventType, header.EventID)
+			return err
+		}
+
+		common.Logger(ctx).Errorf("eventRetry: dead letter id[%s]appid[%s]eventType[%s]attempts[%d]error[%v]",
+			letter.ID, appID, header.EventType, attempts, err)
+		return nil
+	}
+}
+
+var eventRetry *RetryManager
+
+func init() {
+	eventRetry = &RetryManager{
+		mapPolicy: make(map[string]*RetryPolicy, 0),
+		mapSink:   make(map[string]DeadLetterSink, 0),
+	}
+}
+
+// SetEventRetryPolicy demo:
+// event.SetEventRetryPolicy(appID, event.DefaultRetryPolicy())
+//
+// The retries block the response of EventCallback in sync mode, enable async dispatch(see EnableAsyncDispatch)
+// to ack the open platform immediately. A nil policy disables the retry.
+// The retry only re-runs the failed subscribers(see EventSubscribe), but the redrive of the dead letter runs all of them again.
+func SetEventRetryPolicy(appID string, policy *RetryPolicy) error {
+	if appID == "" {
+		return common.ErrEventParams.ErrorWithExtStr("retry appID is empty")
+	}
+	if policy != nil && (policy.MaxAttempts <= 0 || policy.InitialBackoff < 0) {
+		return common.ErrEventParams.ErrorWithExtStr(
+			fmt.Sprintf("retry policy invalid, maxAttempts[%d]initialBackoff[%v]", policy.MaxAttempts, policy.InitialBackoff))
+	}
+
+	eventRetry.SetPolicy(appID, policy)
+	return nil
+}
+
+// SetEventDeadLetterSink the events still failed after the retries are put into the sink, demo:
+// sink, err := event.NewFileDeadLetterSink("./dead_letter.jsonl")
+// event.SetEventDeadLetterSink(appID, sink)
+//
+// A nil sink disables the dead letter.
+func SetEventDeadLetterSink(appID string, sink DeadLetterSink) error {
+	if appID == "" {
+		return common.ErrEventDeadLetter.ErrorWithExtStr("dead letter appID is empty")
+	}
+
+	eventRetry.SetSink(appID, sink)
+	return nil
+}
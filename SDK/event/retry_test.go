@@ -0,0 +1,206 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+func TestEventRetry(t *testing.T) {
+	appID := "cli_test_event_retry"
+	initTestApp(appID)
+
+	called := 0
+	event.EventRegister(appID, protocol.EventTypeAddBot, func(ctx context.Context, eventBody []byte) error {
+		called++
+		if called < 3 {
+			return fmt.Errorf("failed %d", called)
+		}
+		return nil
+	})
+	event.SetEventRetryPolicy(appID, &event.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, Multiplier: 2})
+	defer event.SetEventRetryPolicy(appID, nil)
+
+	_, err := event.EventCallback(context.Background(), newTestEventBody(appID, "retry_1", protocol.EventTypeAddBot), appID)
+	if err != nil || called != 3 {
+		t.Errorf("retry: called[%d]err[%v]", called, err)
+	}
+
+	// retries run out
+	called = -10
+	_, err = event.EventCallback(context.Background(), newTestEventBody(appID, "retry_2", protocol.EventTypeAddBot), appID)
+	if err == nil || called != -7 {
+		t.Errorf("retries run out: called[%d]err[%v]", called, err)
+	}
+
+	policy := &event.RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 3}
+	if policy.Backoff(1) != 100*time.Millisecond || policy.Backoff(2) != 300*time.Millisecond || policy.Backoff(4) != time.Second {
+		t.Errorf("backoff: %v %v %v", policy.Backoff(1), policy.Backoff(2), policy.Backoff(4))
+	}
+}
+
+func TestEventDeadLetter(t *testing.T) {
+	fileSink, err := event.NewFileDeadLetterSink(filepath.Join(t.TempDir(), "dead_letter.jsonl"))
+	if err != nil {
+		t.Fatalf("new file sink: err[%v]", err)
+	}
+	dbSink, _ := event.NewDBDeadLetterSink(common.NewMemoryDBClient(), 0)
+
+	for name, sink := range map[string]event.DeadLetterSink{"file": fileSink, "db": dbSink} {
+		t.Run(name, func(t *testing.T) {
+			appID := "cli_test_dead_letter_" + name
+			initTestApp(appID)
+
+			fail := true
+			event.EventRegister(appID, protocol.EventTypeAddBot, func(ctx context.Context, eventBody []byte) error {
+				if fail {
+					return fmt.Errorf("failed")
+				}
+				return nil
+			})
+			event.SetEventRetryPolicy(appID, &event.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond})
+			event.SetEventDeadLetterSink(appID, sink)
+			defer event.SetEventRetryPolicy(appID, nil)
+			defer event.SetEventDeadLetterSink(appID, nil)
+
+			ctx := context.Background()
+			for i := 0; i < 2; i++ {
+				// acked after put into the sink
+				_, err := event.EventCallback(ctx, newTestEventBody(appID, fmt.Sprintf("dead_letter_%d", i), protocol.EventTypeAddBot), appID)
+				if err != nil {
+					t.Errorf("dead letter: err[%v]", err)
+				}
+			}
+
+			letters, err := sink.List(ctx)
+			if err != nil || len(letters) != 2 {
+				t.Fatalf("list: letters[%d]err[%v]", len(letters), err)
+			}
+			if letters[0].AppID != appID || letters[0].EventType != protocol.EventTypeAddBot || letters[0].Attempts != 2 || letters[0].Error == "" {
+				t.Errorf("letter: %+v", letters[0])
+			}
+
+			// the failed redrive keeps the letter instead of dead-lettering it again
+			count, err := event.RedriveDeadLetters(ctx, sink, nil)
+			kept, _ := sink.List(ctx)
+			if err == nil || count != 0 || len(kept) != 2 || kept[0].ID != letters[0].ID || kept[1].ID != letters[1].ID {
+				t.Errorf("failed redrive: count[%d]letters[%d]err[%v]", count, len(kept), err)
+			}
+
+			// re-drive one letter, the redrive waits for the handler even if async dispatch is enabled
+			fail = false
+			event.EnableAsyncDispatch(nil)
+			defer event.ShutdownAsyncDispatch(ctx)
+			count, err = event.RedriveDeadLetters(ctx, sink, func(letter *event.DeadLetter) bool {
+				return letter.Header.EventID == "dead_letter_0"
+			})
+			if err != nil || count != 1 {
+				t.Errorf("redrive: count[%d]err[%v]", count, err)
+			}
+
+			letters, _ = sink.List(ctx)
+			if len(letters) != 1 || letters[0].Header.EventID != "dead_letter_1" {
+				t.Errorf("after redrive: letters[%d]", len(letters))
+			}
+		})
+	}
+}
+
+func TestDBDeadLetterSinkConcurrent(t *testing.T) {
+	db := common.NewMemoryDBClient()
+
+	// the instances sharing the db don't lose the ids of each other
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		sink, _ := event.NewDBDeadLetterSink(db, 0)
+		wg.Add(1)
+		go func(i int, sink *event.DBDeadLetterSink) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				sink.Put(ctx, &event.DeadLetter{ID: fmt.Sprintf("letter_%d_%d", i, j)})
+			}
+		}(i, sink)
+	}
+	wg.Wait()
+
+	sink, _ := event.NewDBDeadLetterSink(db, 0)
+	letters, err := sink.List(ctx)
+	if err != nil || len(letters) != 40 {
+		t.Errorf("list: letters[%d]err[%v]", len(letters), err)
+	}
+}
+
+func TestDBDeadLetterSinkLock(t *testing.T) {
+	db := common.NewMemoryDBClient()
+
+	unlock, err := common.TryLock(db, "dead_letter_lock", 10*time.Millisecond)
+	if err != nil || unlock == nil {
+		t.Fatalf("lock: err[%v]", err)
+	}
+	if again, _ := common.TryLock(db, "dead_letter_lock", time.Second); again != nil {
+		t.Errorf("locked key should not be locked again")
+	}
+
+	// the expired lock is taken by another holder, the first unlock keeps it
+	time.Sleep(20 * time.Millisecond)
+	other, err := common.TryLock(db, "dead_letter_lock", time.Second)
+	if err != nil || other == nil {
+		t.Fatalf("lock after expiration: err[%v]", err)
+	}
+	unlock()
+	if again, _ := common.TryLock(db, "dead_letter_lock", time.Second); again != nil {
+		t.Errorf("unlock should not delete the lock of another holder")
+	}
+
+	other()
+	if again, _ := common.TryLock(db, "dead_letter_lock", time.Second); again == nil {
+		t.Errorf("unlock should delete the own lock")
+	}
+}
+
+func TestEventRetryFanOut(t *testing.T) {
+	appID := "cli_test_event_retry_fan_out"
+	initTestApp(appID)
+
+	succeeded, failed := 0, 0
+	event.EventSubscribe(appID, protocol.EventTypeAddBot, func(ctx context.Context, eventBody []byte) error {
+		succeeded++
+		return nil
+	})
+	event.EventSubscribe(appID, protocol.EventTypeAddBot, func(ctx context.Context, eventBody []byte) error {
+		failed++
+		if failed < 3 {
+			return fmt.Errorf("failed %d", failed)
+		}
+		return nil
+	})
+	event.SetEventRetryPolicy(appID, &event.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+	defer event.SetEventRetryPolicy(appID, nil)
+
+	// FanOutAll: the succeeded subscriber is not re-run
+	ctx := context.Background()
+	_, err := event.EventCallback(ctx, newTestEventBody(appID, "retry_fan_out_1", protocol.EventTypeAddBot), appID)
+	if err != nil || succeeded != 1 || failed != 3 {
+		t.Errorf("FanOutAll: succeeded[%d]failed[%d]err[%v]", succeeded, failed, err)
+	}
+
+	// FanOutFirstError: the retry resumes from the failed subscriber
+	succeeded, failed = 0, 0
+	event.SetEventFanOutPolicy(appID, event.FanOutFirstError)
+	_, err = event.EventCallback(ctx, newTestEventBody(appID, "retry_fan_out_2", protocol.EventTypeAddBot), appID)
+	if err != nil || succeeded != 1 || failed != 3 {
+		t.Errorf("FanOutFirstError: succeeded[%d]failed[%d]err[%v]", succeeded, failed, err)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"context"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+)
+
+// Router owns the registries of event handlers, bot commands and card actions.
+// The package functions(EventRegister, BotRecvMsgRegister, CardRegister, EventCallback ...) use the default router,
+// create a new router to isolate the handlers of tests or of multiple bots in one process. demo:
+//
+// router := event.NewRouter()
+//
+//	router.EventRegister(appID, protocol.EventTypeMessage, func(ctx context.Context, eventBody []byte) error {
+//		return router.BotRecvMsgHandler(ctx, eventBody)
+//	})
+//
+// router.BotRecvMsgRegister(appID, "help", BotRecvMsgHelp)
+// challenge, err := router.EventCallbackWithHeader(ctx, body, appID, header)
+//
+// The registries are safe for concurrent use, handlers can be registered, replaced and unregistered while dispatching.
+// The other options(middleware, dedup, async dispatch, signature ...) are shared by all routers, they are also safe to change while dispatching.
+type Router struct {
+	eventManager   *EventHandlerManager
+	cmdHandler     *CommandHandlerManager
+	cardHandler    *ActionHandlerManager
+	ruleManager    *EventRuleManager
+	dialogManager  *DialogManager
+	matcherManager *MessageMatcherManager
+	drainer        *common.Drainer
+}
+
+func NewRouter() *Router {
+	return &Router{
+		eventManager:   newEventHandlerManager(),
+		cmdHandler:     newCommandHandlerManager(),
+		cardHandler:    newActionHandlerManager(),
+		ruleManager:    newEventRuleManager(),
+		dialogManager:  newDialogManager(),
+		matcherManager: newMessageMatcherManager(),
+		drainer:        &common.Drainer{},
+	}
+}
+
+var defaultRouter = NewRouter()
+
+// DefaultRouter the router used by the package functions
+func DefaultRouter() *Router {
+	return defaultRouter
+}
+
+// Shutdown reject the new callbacks with ErrEventShuttingDown, and wait for the callbacks being handled.
+// The events queued by the async dispatch are not waited, see ShutdownAsyncDispatch.
+// Return error if ctx is done before all callbacks return. The router can't be used after Shutdown.
+func (r *Router) Shutdown(ctx context.Context) error {
+	return r.drainer.Drain(ctx)
+}
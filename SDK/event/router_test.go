@@ -0,0 +1,156 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+func TestRouterIsolation(t *testing.T) {
+	appID := "cli_test_router_isolation"
+	initTestApp(appID)
+
+	router := event.NewRouter()
+	called := ""
+	router.EventRegister(appID, protocol.EventTypeAddBot, func(ctx context.Context, eventBody []byte) error {
+		called = "router"
+		return nil
+	})
+
+	ctx := context.Background()
+	_, err := router.EventCallback(ctx, newTestEventBody(appID, "router_1", protocol.EventTypeAddBot), appID)
+	if err != nil || called != "router" {
+		t.Errorf("router: called[%s]err[%v]", called, err)
+	}
+
+	// the default router doesn't see the handlers of the router
+	_, err = event.EventCallback(ctx, newTestEventBody(appID, "router_2", protocol.EventTypeAddBot), appID)
+	if err == nil || !strings.Contains(err.Error(), common.ErrEventAppIDUnregistered.String()) {
+		t.Errorf("default router: err[%v]", err)
+	}
+}
+
+func TestRouterUnregisterReplace(t *testing.T) {
+	appID := "cli_test_router_replace"
+	initTestApp(appID)
+
+	router := event.NewRouter()
+	ctx := context.Background()
+
+	// replace before register
+	err := router.EventReplace(appID, protocol.EventTypeAddBot, func(ctx context.Context, eventBody []byte) error { return nil })
+	if err == nil {
+		t.Errorf("replace unregistered event: err is nil")
+	}
+
+	called := ""
+	router.EventRegister(appID, protocol.EventTypeAddBot, func(ctx context.Context, eventBody []byte) error {
+		called = "old"
+		return nil
+	})
+	router.EventRegister(appID, protocol.EventTypeRemoveBot, func(ctx context.Context, eventBody []byte) error {
+		return nil
+	})
+	err = router.EventReplace(appID, protocol.EventTypeAddBot, func(ctx context.Context, eventBody []byte) error {
+		called = "new"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("replace: err[%v]", err)
+	}
+	_, err = router.EventCallback(ctx, newTestEventBody(appID, "replace_1", protocol.EventTypeAddBot), appID)
+	if err != nil || called != "new" {
+		t.Errorf("replaced handler: called[%s]err[%v]", called, err)
+	}
+
+	// unregister
+	if err = router.EventUnregister(appID, protocol.EventTypeAddBot); err != nil {
+		t.Errorf("unregister: err[%v]", err)
+	}
+	_, err = router.EventCallback(ctx, newTestEventBody(appID, "replace_2", protocol.EventTypeAddBot), appID)
+	if err == nil || !strings.Contains(err.Error(), common.ErrEventTypeUnregistered.String()) {
+		t.Errorf("unregistered handler: err[%v]", err)
+	}
+	if err = router.EventUnregister(appID, protocol.EventTypeAddBot); err == nil {
+		t.Errorf("unregister twice: err is nil")
+	}
+
+	// bot command
+	router.BotRecvMsgRegister(appID, "help", func(ctx context.Context, msg *protocol.BotRecvMsg) error { return nil })
+	if err = router.BotRecvMsgReplace(appID, "HELP", func(ctx context.Context, msg *protocol.BotRecvMsg) error { return nil }); err != nil {
+		t.Errorf("replace command: err[%v]", err)
+	}
+	if err = router.BotRecvMsgUnregister(appID, "help"); err != nil {
+		t.Errorf("unregister command: err[%v]", err)
+	}
+	if err = router.BotRecvMsgReplace(appID, "help", func(ctx context.Context, msg *protocol.BotRecvMsg) error { return nil }); err == nil {
+		t.Errorf("replace unregistered command: err is nil")
+	}
+
+	// card method
+	router.CardRegister(appID, "click", func(ctx context.Context, cardCallback *protocol.CardCallbackForm) (*protocol.CardForm, error) {
+		return nil, nil
+	})
+	if err = router.CardUnregister(appID, "click"); err != nil {
+		t.Errorf("unregister method: err[%v]", err)
+	}
+	if err = router.CardUnregister(appID, "click"); err == nil {
+		t.Errorf("unregister method twice: err is nil")
+	}
+}
+
+func TestRouterConcurrent(t *testing.T) {
+	appID := "cli_test_router_concurrent"
+	initTestApp(appID)
+
+	router := event.NewRouter()
+	router.EventRegister(appID, protocol.EventTypeAddBot, func(ctx context.Context, eventBody []byte) error { return nil })
+
+	// the global options are changed while dispatching, other app keeps the callbacks passing
+	optionAppID := appID + "_options"
+	defer event.DisableEventDedup()
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				router.EventCallback(ctx, newTestEventBody(appID, fmt.Sprintf("concurrent_%d_%d", i, j), protocol.EventTypeAddBot), appID)
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				router.EventSubscribe(appID, protocol.EventTypeAddBot, func(ctx context.Context, eventBody []byte) error { return nil })
+				router.EventReplace(appID, protocol.EventTypeAddBot, func(ctx context.Context, eventBody []byte) error { return nil })
+				router.IgnoreSign(appID, j%2 == 0)
+				router.BotRecvMsgRegister(appID, fmt.Sprintf("cmd_%d", i), func(ctx context.Context, msg *protocol.BotRecvMsg) error { return nil })
+				router.BotRecvMsgUnregister(appID, fmt.Sprintf("cmd_%d", i))
+
+				event.SetEventSignatureCheck(optionAppID, event.DefaultSignatureOption())
+				event.SetCardReplayProtection(optionAppID, &event.ReplayOption{})
+				event.EventKeyExtractorRegister(optionAppID, event.KeyByChatID)
+				event.UseAppEventMiddleware(optionAppID, event.RecoveryEventMiddleware())
+				event.UseAppBotMsgMiddleware(optionAppID, event.RecoveryBotMsgMiddleware())
+				if j%2 == 0 {
+					event.EnableEventDedup(common.NewMemoryDBClient(), 0)
+				} else {
+					event.DisableEventDedup()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,250 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+)
+
+const (
+	suspendedTenantKeyPrefix = "suspended_tenant:"
+)
+
+// EventRule route the matched events to another handler, or drop them.
+// The conditions are combined by AND, an empty condition matches all events.
+type EventRule struct {
+	Name          string   // unique in the app, used to unregister the rule and in the log
+	EventTypes    []string // the event types
+	Tenants       []string // the tenant keys
+	ExceptTenants []string // the tenant keys NOT matched, eg: the allow list of a drop rule
+	ChatTypes     []string // schema 1.0: private/group, schema 2.0: p2p/group
+	ChatIDs       []string
+	Senders       []string // the open ids of the sender/operator
+	Predicate     func(ctx context.Context, meta *EventMeta) bool
+
+	Handler EventHandler // route the matched events to the handler, nil means drop them
+}
+
+func (rule *EventRule) match(ctx context.Context, meta *EventMeta) bool {
+	if len(rule.EventTypes) != 0 && !contains(rule.EventTypes, meta.EventType) {
+		return false
+	}
+	if len(rule.Tenants) != 0 && !contains(rule.Tenants, meta.TenantKey) {
+		return false
+	}
+	if contains(rule.ExceptTenants, meta.TenantKey) {
+		return false
+	}
+	if len(rule.ChatTypes) != 0 && !contains(rule.ChatTypes, meta.ChatType) {
+		return false
+	}
+	if len(rule.ChatIDs) != 0 && !contains(rule.ChatIDs, meta.ChatID) {
+		return false
+	}
+	if len(rule.Senders) != 0 && !contains(rule.Senders, meta.OpenID) {
+		return false
+	}
+	if rule.Predicate != nil && !rule.Predicate(ctx, meta) {
+		return false
+	}
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// EventRuleManager the rules of each app, the drop rules are evaluated before the routing rules
+type EventRuleManager struct {
+	mapRule map[string][]*EventRule //[app_id][rules]
+	rwMu    sync.RWMutex
+}
+
+func newEventRuleManager() *EventRuleManager {
+	return &EventRuleManager{
+		mapRule: make(map[string][]*EventRule, 0),
+	}
+}
+
+// Add append the rule, return false if the name has been registered in the app
+func (m *EventRuleManager) Add(appID string, rule *EventRule) bool {
+	m.rwMu.Lock()
+	defer m.rwMu.Unlock()
+
+	for _, r := range m.mapRule[appID] {
+		if r.Name == rule.Name {
+			return false
+		}
+	}
+	m.mapRule[appID] = append(m.mapRule[appID], rule)
+	return true
+}
+
+// Delete remove the rule, return false if the name has not been registered in the app
+func (m *EventRuleManager) Delete(appID, name string) bool {
+	m.rwMu.Lock()
+	defer m.rwMu.Unlock()
+
+	rules := m.mapRule[appID]
+	for i := range rules {
+		if rules[i].Name == name {
+			// copy on write, the rules being evaluated are not changed
+			m.mapRule[appID] = append(append([]*EventRule{}, rules[:i]...), rules[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Match return the first drop rule matches the event, or the first routing rule if no drop rule matches, nil if none.
+// So a routing rule never lets the events of the denied or suspended tenants through.
+func (m *EventRuleManager) Match(ctx context.Context, appID string, meta *EventMeta) *EventRule {
+	m.rwMu.RLock()
+	rules := m.mapRule[appID]
+	m.rwMu.RUnlock()
+
+	for _, rule := range rules {
+		if rule.Handler == nil && rule.match(ctx, meta) {
+			return rule
+		}
+	}
+	for _, rule := range rules {
+		if rule.Handler != nil && rule.match(ctx, meta) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// EventRuleRegister demo:
+// // route the message events of the tenant to the beta handler
+// event.EventRuleRegister(appID, &event.EventRule{
+// 	Name:       "beta",
+// 	EventTypes: []string{protocol.EventTypeMessage},
+// 	Tenants:    []string{"tenant_beta"},
+// 	Handler:    EventMessageBeta,
+// })
+// // drop the events of the tenants not in the allow list
+// event.EventRuleRegister(appID, event.AllowTenants("allow", "tenant_a", "tenant_b"))
+//
+// The rules are evaluated before dispatching: the drop rules first, then the routing rules, each in the order of registration.
+// The first matched one is applied.
+// The event matches no rule is dispatched to the handlers registered by EventRegister.
+func EventRuleRegister(appID string, rule *EventRule) error {
+	return defaultRouter.EventRuleRegister(appID, rule)
+}
+
+// EventRuleUnregister remove the rule by name
+func EventRuleUnregister(appID, name string) error {
+	return defaultRouter.EventRuleUnregister(appID, name)
+}
+
+func (r *Router) EventRuleRegister(appID string, rule *EventRule) error {
+	if appID == "" || rule == nil || rule.Name == "" {
+		return common.ErrEventRuleRegister.ErrorWithExtStr(
+			fmt.Sprintf("params is empty or nil. AppID[%s]RuleIsNil[%t]", appID, rule == nil))
+	}
+
+	if !r.ruleManager.Add(appID, rule) {
+		return common.ErrEventRuleRegister.ErrorWithExtStr(fmt.Sprintf("appid[%s]rule[%s] has been registered", appID, rule.Name))
+	}
+
+	return nil
+}
+
+func (r *Router) EventRuleUnregister(appID, name string) error {
+	if appID == "" || name == "" {
+		return common.ErrEventRuleRegister.ErrorWithExtStr(fmt.Sprintf("params is empty. AppID[%s]Rule[%s]", appID, name))
+	}
+
+	if !r.ruleManager.Delete(appID, name) {
+		return common.ErrEventRuleRegister.ErrorWithExtStr(fmt.Sprintf("appid[%s]rule[%s] has not been registered", appID, name))
+	}
+
+	return nil
+}
+
+// AllowTenants drop the events of the other tenants
+func AllowTenants(name string, tenantKeys ...string) *EventRule {
+	return &EventRule{Name: name, ExceptTenants: tenantKeys}
+}
+
+// DenyTenants drop the events of the tenants
+func DenyTenants(name string, tenantKeys ...string) *EventRule {
+	return &EventRule{Name: name, Tenants: tenantKeys}
+}
+
+// TenantStore the suspended tenants, eg: the tenants whose subscription expired
+type TenantStore interface {
+	IsSuspended(ctx context.Context, appID, tenantKey string) (bool, error)
+}
+
+// DropSuspendedTenants drop the events of the tenants suspended in the store.
+// The event is not dropped if the store returns error.
+func DropSuspendedTenants(name string, store TenantStore) *EventRule {
+	return &EventRule{
+		Name: name,
+		Predicate: func(ctx context.Context, meta *EventMeta) bool {
+			if meta.TenantKey == "" {
+				return false
+			}
+
+			suspended, err := store.IsSuspended(ctx, meta.AppID, meta.TenantKey)
+			if err != nil {
+				common.Logger(ctx).Warnf("eventRule: tenantStoreError[%v]appid[%s]tenant[%s]", err, meta.AppID, meta.TenantKey)
+				return false
+			}
+			return suspended
+		},
+	}
+}
+
+// DBTenantStore store the suspended tenants by common.DBClient
+type DBTenantStore struct {
+	client common.DBClient
+}
+
+func NewDBTenantStore(client common.DBClient) (*DBTenantStore, error) {
+	if client == nil {
+		return nil, common.ErrEventRuleRegister.ErrorWithExtStr("db client is nil")
+	}
+
+	return &DBTenantStore{client: client}, nil
+}
+
+// Suspend expiration 0 means the tenant is suspended until Resume
+func (s *DBTenantStore) Suspend(appID, tenantKey string, expiration time.Duration) error {
+	return s.client.Set(suspendedTenantKey(appID, tenantKey), "1", expiration)
+}
+
+func (s *DBTenantStore) Resume(appID, tenantKey string) error {
+	if client, ok := s.client.(common.DBClientEx); ok {
+		return client.Del(suspendedTenantKey(appID, tenantKey))
+	}
+	return s.client.Set(suspendedTenantKey(appID, tenantKey), "", time.Millisecond)
+}
+
+func (s *DBTenantStore) IsSuspended(ctx context.Context, appID, tenantKey string) (bool, error) {
+	value, err := s.client.Get(suspendedTenantKey(appID, tenantKey))
+	if err != nil || value == "" {
+		// not found or expired
+		return false, nil
+	}
+	return true, nil
+}
+
+func suspendedTenantKey(appID, tenantKey string) string {
+	return suspendedTenantKeyPrefix + appID + ":" + tenantKey
+}
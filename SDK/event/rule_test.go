@@ -0,0 +1,143 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+func TestEventRule(t *testing.T) {
+	appID := "cli_test_event_rule"
+	initTestApp(appID)
+
+	router := event.NewRouter()
+	called := ""
+	router.EventRegister(appID, protocol.EventTypeMessage, func(ctx context.Context, eventBody []byte) error {
+		called = "default"
+		return nil
+	})
+
+	err := router.EventRuleRegister(appID, &event.EventRule{
+		Name:       "beta",
+		EventTypes: []string{protocol.EventTypeMessage},
+		Tenants:    []string{"tenant_beta"},
+		Handler: func(ctx context.Context, eventBody []byte) error {
+			called = "beta"
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("register beta rule: err[%v]", err)
+	}
+	router.EventRuleRegister(appID, &event.EventRule{
+		Name:      "drop_p2p_of_ou_1",
+		ChatTypes: []string{"private", "p2p"},
+		Senders:   []string{"ou_1"},
+	})
+
+	// the name is unique in the app
+	if err = router.EventRuleRegister(appID, &event.EventRule{Name: "beta"}); err == nil {
+		t.Errorf("register duplicate rule: err is nil")
+	}
+
+	ctx := context.Background()
+	cases := []struct {
+		tenantKey string
+		chatType  string
+		expected  string
+	}{
+		{"tenant_beta", "group", "beta"},
+		{"tenant_beta", "private", ""}, // the drop rule is applied before the routing rule
+		{"tenant_a", "group", "default"},
+		{"tenant_a", "private", ""},
+	}
+	for i, c := range cases {
+		called = ""
+		_, err = router.EventCallback(ctx, newTestEventBody(appID, fmt.Sprintf("rule_%d", i), protocol.EventTypeMessage, testFields{"tenant_key": c.tenantKey, "chat_type": c.chatType}), appID)
+		if err != nil || called != c.expected {
+			t.Errorf("case[%d]: called[%s]expected[%s]err[%v]", i, called, c.expected, err)
+		}
+	}
+
+	// the event is dispatched by EventRegister after the rule is removed
+	if err = router.EventRuleUnregister(appID, "beta"); err != nil {
+		t.Errorf("unregister beta rule: err[%v]", err)
+	}
+	if err = router.EventRuleUnregister(appID, "beta"); err == nil {
+		t.Errorf("unregister beta rule again: err is nil")
+	}
+	called = ""
+	_, err = router.EventCallback(ctx, newTestEventBody(appID, "rule_after_unregister", protocol.EventTypeMessage, testFields{"tenant_key": "tenant_beta", "chat_type": "group"}), appID)
+	if err != nil || called != "default" {
+		t.Errorf("after unregister: called[%s]err[%v]", called, err)
+	}
+}
+
+func TestEventRuleTenantList(t *testing.T) {
+	appID := "cli_test_event_rule_tenant"
+	initTestApp(appID)
+
+	router := event.NewRouter()
+	called := 0
+	router.EventRegister(appID, protocol.EventTypeAddBot, func(ctx context.Context, eventBody []byte) error {
+		called++
+		return nil
+	})
+
+	db := &common.MemoryDBClient{}
+	db.InitDB(nil)
+	store, _ := event.NewDBTenantStore(db)
+	store.Suspend(appID, "tenant_suspended", 0)
+
+	// the routing rule registered first does not let the dropped tenants through
+	router.EventRuleRegister(appID, &event.EventRule{
+		Name:    "route_all",
+		Handler: func(ctx context.Context, eventBody []byte) error { called++; return nil },
+	})
+	router.EventRuleRegister(appID, event.DenyTenants("deny", "tenant_denied"))
+	router.EventRuleRegister(appID, event.AllowTenants("allow", "tenant_a", "tenant_suspended"))
+	router.EventRuleRegister(appID, event.DropSuspendedTenants("suspended", store))
+
+	ctx := context.Background()
+	cases := []struct {
+		tenantKey string
+		expected  int
+	}{
+		{"tenant_a", 1},
+		{"tenant_denied", 0},
+		{"tenant_b", 0},
+		{"tenant_suspended", 0},
+	}
+	for i, c := range cases {
+		called = 0
+		_, err := router.EventCallback(ctx, newTestEventBody(appID, fmt.Sprintf("tenant_%d", i), protocol.EventTypeAddBot, testFields{"tenant_key": c.tenantKey}), appID)
+		if err != nil || called != c.expected {
+			t.Errorf("tenant[%s]: called[%d]err[%v]", c.tenantKey, called, err)
+		}
+	}
+
+	// resume the tenant
+	store.Resume(appID, "tenant_suspended")
+	called = 0
+	_, err := router.EventCallback(ctx, newTestEventBody(appID, "tenant_resumed", protocol.EventTypeAddBot, testFields{"tenant_key": "tenant_suspended"}), appID)
+	if err != nil || called != 1 {
+		t.Errorf("resumed tenant: called[%d]err[%v]", called, err)
+	}
+
+	// the suspension expires
+	store.Suspend(appID, "tenant_a", 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	suspended, _ := store.IsSuspended(ctx, appID, "tenant_a")
+	if suspended {
+		t.Errorf("expired suspension: suspended")
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/bitly/go-simplejson"
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+func TestEventSchemaV2(t *testing.T) {
+	appID := "cli_test_event_schema_v2"
+	initTestApp(appID)
+
+	var header *protocol.EventHeader
+	var chatID string
+	event.EventRegister(appID, "im.chat.member.bot.added_v1", func(ctx context.Context, eventBody []byte) error {
+		header = event.EventHeaderFromContext(ctx)
+
+		jsonEvent, _ := simplejson.NewJson(eventBody)
+		chatID, _ = jsonEvent.Get("chat_id").String()
+		return nil
+	})
+
+	body := `{"schema":"2.0","header":{"event_id":"evt_1","event_type":"im.chat.member.bot.added_v1","create_time":"1608725989000","token":"%s","app_id":"%s","tenant_key":"tenant_v2"},"event":{"chat_id":"oc_v2"}}`
+
+	ctx := context.Background()
+	_, err := event.EventCallback(ctx, fmt.Sprintf(body, testVerifyToken, appID), appID)
+	if err != nil {
+		t.Fatalf("EventCallback v2 failed: err[%v]", err)
+	}
+	if header == nil || header.Schema != protocol.EventSchemaV2 || header.EventID != "evt_1" || header.TenantKey != "tenant_v2" {
+		t.Errorf("v2 header: header[%+v]", header)
+	}
+	if chatID != "oc_v2" {
+		t.Errorf("v2 event body: chatID[%s]", chatID)
+	}
+
+	// invalid token
+	_, err = event.EventCallback(ctx, fmt.Sprintf(body, "invalid", appID), appID)
+	if err == nil {
+		t.Errorf("v2 invalid token should fail")
+	}
+
+	// app id not match
+	_, err = event.EventCallback(ctx, fmt.Sprintf(body, testVerifyToken, "cli_other"), appID)
+	if err == nil {
+		t.Errorf("v2 app id not match should fail")
+	}
+}
+
+func TestEventSchemaV1Header(t *testing.T) {
+	appID := "cli_test_event_schema_v1"
+	initTestApp(appID)
+
+	var header *protocol.EventHeader
+	event.EventRegister(appID, protocol.EventTypeAddBot, func(ctx context.Context, eventBody []byte) error {
+		header = event.EventHeaderFromContext(ctx)
+		return nil
+	})
+
+	_, err := event.EventCallback(context.Background(), newTestEventBody(appID, "v1_uuid", protocol.EventTypeAddBot), appID)
+	if err != nil {
+		t.Fatalf("EventCallback v1 failed: err[%v]", err)
+	}
+	if header == nil || header.Schema != protocol.EventSchemaV1 || header.EventID != "v1_uuid" ||
+		header.EventType != protocol.EventTypeAddBot || header.AppID != appID || header.TenantKey != "tenant" {
+		t.Errorf("v1 header: header[%+v]", header)
+	}
+}
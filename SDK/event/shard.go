@@ -0,0 +1,103 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"hash/fnv"
+	"sync"
+
+	"github.com/bitly/go-simplejson"
+	"github.com/larksuite/botframework-go/SDK/common"
+)
+
+// KeyExtractor get the shard key of the event in async mode.
+// The events with the same key are handled in order, the events with different keys are handled in parallel.
+// Return "" if the event need not be ordered.
+type KeyExtractor func(eventType string, eventBody []byte) string
+
+var (
+	// KeyByChatID events of the same chat are handled in order, the same paths as EventMeta.ChatID
+	KeyByChatID KeyExtractor = keyByJsonPath(metaChatIDPaths)
+	// KeyByOpenID events of the same user are handled in order, the same paths as EventMeta.OpenID
+	KeyByOpenID KeyExtractor = keyByJsonPath(metaOpenIDPaths)
+	// KeyByTenantKey events of the same tenant are handled in order
+	KeyByTenantKey KeyExtractor = keyByJsonPath([][]string{{"tenant_key"}})
+)
+
+type KeyExtractorManager struct {
+	mapExtractor map[string]KeyExtractor // appID => extractor
+	rwMu         sync.RWMutex
+}
+
+func (k *KeyExtractorManager) Set(appID string, extractor KeyExtractor) {
+	k.rwMu.Lock()
+	defer k.rwMu.Unlock()
+
+	if extractor == nil {
+		delete(k.mapExtractor, appID)
+		return
+	}
+
+	k.mapExtractor[appID] = extractor
+}
+
+func (k *KeyExtractorManager) Get(appID string) KeyExtractor {
+	k.rwMu.RLock()
+	defer k.rwMu.RUnlock()
+
+	return k.mapExtractor[appID]
+}
+
+var keyExtractorManager *KeyExtractorManager
+
+func init() {
+	keyExtractorManager = &KeyExtractorManager{
+		mapExtractor: make(map[string]KeyExtractor, 0),
+	}
+}
+
+// EventKeyExtractorRegister demo:
+// event.EventRegister(appID, protocol.EventTypeMessage, EventMessage)
+// event.EventKeyExtractorRegister(appID, event.KeyByChatID)
+//
+// It only works in async mode, see EnableAsyncDispatch. A nil extractor removes the registered one.
+func EventKeyExtractorRegister(appID string, extractor KeyExtractor) error {
+	if appID == "" {
+		return common.ErrEventTypeRegister.ErrorWithExtStr("key extractor appID is empty")
+	}
+
+	keyExtractorManager.Set(appID, extractor)
+	return nil
+}
+
+func getShardKey(appID, eventType string, eventBody []byte) string {
+	extractor := keyExtractorManager.Get(appID)
+	if extractor == nil {
+		return ""
+	}
+
+	key := extractor(eventType, eventBody)
+	if key == "" {
+		return ""
+	}
+	return appID + ":" + key
+}
+
+func shardIndex(key string, shardNum int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(shardNum))
+}
+
+func keyByJsonPath(paths [][]string) KeyExtractor {
+	return func(eventType string, eventBody []byte) string {
+		jsonBody, err := simplejson.NewJson(eventBody)
+		if err != nil {
+			return ""
+		}
+
+		return getJsonString(jsonBody, paths)
+	}
+}
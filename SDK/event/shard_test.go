@@ -0,0 +1,105 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+func TestAsyncDispatchOrderedByChat(t *testing.T) {
+	appID := "cli_test_async_shard"
+	initTestApp(appID)
+
+	err := event.EnableAsyncDispatch(&event.AsyncOption{WorkerNum: 4, QueueSize: 100})
+	if err != nil {
+		t.Fatalf("EnableAsyncDispatch failed: err[%v]", err)
+	}
+	event.EventKeyExtractorRegister(appID, event.KeyByChatID)
+	defer event.EventKeyExtractorRegister(appID, nil)
+
+	var mu sync.Mutex
+	received := map[string][]int{}
+	event.EventRegister(appID, protocol.EventTypeMessage, func(ctx context.Context, eventBody []byte) error {
+		msg := &protocol.TextMsgEvent{}
+		json.Unmarshal(eventBody, msg)
+
+		// the earlier events are slower
+		var seq int
+		fmt.Sscanf(msg.Text, "%d", &seq)
+		time.Sleep(time.Duration(10-seq) * time.Millisecond)
+
+		mu.Lock()
+		received[msg.OpenChatID] = append(received[msg.OpenChatID], seq)
+		mu.Unlock()
+		return nil
+	})
+
+	ctx := context.Background()
+	chats := []string{"oc_a", "oc_b", "oc_c"}
+	for seq := 0; seq < 10; seq++ {
+		for _, chat := range chats {
+			body := fmt.Sprintf(`{"uuid":"%s_%d","token":"%s","type":"event_callback","event":{"type":"message","app_id":"%s","msg_type":"text","open_chat_id":"%s","text":"%d"}}`,
+				chat, seq, testVerifyToken, appID, chat, seq)
+			_, err := event.EventCallback(ctx, body, appID)
+			if err != nil {
+				t.Fatalf("EventCallback failed: err[%v]", err)
+			}
+		}
+	}
+
+	err = event.ShutdownAsyncDispatch(ctx)
+	if err != nil {
+		t.Errorf("ShutdownAsyncDispatch failed: err[%v]", err)
+	}
+
+	for _, chat := range chats {
+		seqs := received[chat]
+		if len(seqs) != 10 {
+			t.Errorf("chat[%s] received[%v]", chat, seqs)
+			continue
+		}
+		for i, seq := range seqs {
+			if seq != i {
+				t.Errorf("chat[%s] out of order: received[%v]", chat, seqs)
+				break
+			}
+		}
+	}
+}
+
+func TestKeyExtractor(t *testing.T) {
+	body := []byte(`{"type":"add_user_to_chat","tenant_key":"tenant_1","chat_id":"oc_1","open_id":"ou_1"}`)
+
+	if key := event.KeyByChatID("add_user_to_chat", body); key != "oc_1" {
+		t.Errorf("KeyByChatID: key[%s]", key)
+	}
+	if key := event.KeyByOpenID("add_user_to_chat", body); key != "ou_1" {
+		t.Errorf("KeyByOpenID: key[%s]", key)
+	}
+	if key := event.KeyByTenantKey("add_user_to_chat", body); key != "tenant_1" {
+		t.Errorf("KeyByTenantKey: key[%s]", key)
+	}
+
+	// schema 2.0
+	body = []byte(`{"message":{"chat_id":"oc_2","chat_type":"group"},"sender":{"sender_id":{"open_id":"ou_2"}}}`)
+	if key := event.KeyByChatID("im.message.receive_v1", body); key != "oc_2" {
+		t.Errorf("KeyByChatID schema 2.0: key[%s]", key)
+	}
+	if key := event.KeyByOpenID("im.message.receive_v1", body); key != "ou_2" {
+		t.Errorf("KeyByOpenID schema 2.0: key[%s]", key)
+	}
+
+	if key := event.KeyByChatID("app_ticket", []byte(`{"type":"app_ticket"}`)); key != "" {
+		t.Errorf("KeyByChatID without chat: key[%s]", key)
+	}
+}
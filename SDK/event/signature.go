@@ -0,0 +1,134 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+)
+
+const (
+	HeaderRequestTimestamp = "X-Lark-Request-Timestamp"
+	HeaderRequestNonce     = "X-Lark-Request-Nonce"
+	HeaderSignature        = "X-Lark-Signature"
+
+	DefaultTimestampWindow = 5 * time.Minute
+)
+
+type SignatureOption struct {
+	TimestampWindow time.Duration // reject the request whose timestamp is outside the window, 0 means no check
+}
+
+func DefaultSignatureOption() *SignatureOption {
+	return &SignatureOption{
+		TimestampWindow: DefaultTimestampWindow,
+	}
+}
+
+type SignatureManager struct {
+	mapOption map[string]*SignatureOption // appID => option
+	rwMu      sync.RWMutex
+}
+
+func (s *SignatureManager) Set(appID string, option *SignatureOption) {
+	s.rwMu.Lock()
+	defer s.rwMu.Unlock()
+
+	if option == nil {
+		delete(s.mapOption, appID)
+		return
+	}
+
+	s.mapOption[appID] = option
+}
+
+func (s *SignatureManager) Get(appID string) *SignatureOption {
+	s.rwMu.RLock()
+	defer s.rwMu.RUnlock()
+
+	return s.mapOption[appID]
+}
+
+var eventSignature *SignatureManager
+
+func init() {
+	eventSignature = &SignatureManager{
+		mapOption: make(map[string]*SignatureOption, 0),
+	}
+}
+
+// SetEventSignatureCheck verify the X-Lark-Signature of the event callback, the app must set EncryptKey. demo:
+// event.SetEventSignatureCheck(appID, event.DefaultSignatureOption())
+//
+// The request headers should be passed by EventCallbackWithHeader. A nil option disables the check.
+func SetEventSignatureCheck(appID string, option *SignatureOption) {
+	eventSignature.Set(appID, option)
+}
+
+// verifyEventSignature signature = sha256(timestamp + nonce + encryptKey + body)
+// The timestamp window is not checked for the replayed event, see WithReplay.
+func verifyEventSignature(ctx context.Context, option *SignatureOption, encryptKey string, header map[string]string, body string) error {
+	if encryptKey == "" {
+		return common.ErrEventSignatureInvalid.ErrorWithExtStr("encrypt key is empty")
+	}
+
+	timestamp := header[HeaderRequestTimestamp]
+	nonce := header[HeaderRequestNonce]
+	sig := header[HeaderSignature]
+	if timestamp == "" || nonce == "" || sig == "" {
+		return common.ErrEventSignatureInvalid.ErrorWithExtStr("signature header is empty")
+	}
+
+	if !IsReplay(ctx) {
+		err := checkTimestamp(timestamp, option.TimestampWindow)
+		if err != nil {
+			return common.ErrEventTimestampExpired.ErrorWithExtErr(err)
+		}
+	}
+
+	// constant time comparison
+	if !hmac.Equal([]byte(genEventSignature(timestamp, nonce, encryptKey, body)), []byte(sig)) {
+		return common.ErrEventSignatureInvalid.Error()
+	}
+
+	return nil
+}
+
+func genEventSignature(timestamp, nonce, encryptKey, body string) string {
+	var b strings.Builder
+	b.WriteString(timestamp)
+	b.WriteString(nonce)
+	b.WriteString(encryptKey)
+	b.WriteString(body)
+
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(b.String())))
+}
+
+// checkTimestamp timestamp unit: second
+func checkTimestamp(timestamp string, window time.Duration) error {
+	if window <= 0 {
+		return nil
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("timestamp[%s] invalid", timestamp)
+	}
+
+	diff := time.Since(time.Unix(ts, 0))
+	if diff > window || diff < -window {
+		return fmt.Errorf("timestamp[%s] outside window[%v]", timestamp, window)
+	}
+
+	return nil
+}
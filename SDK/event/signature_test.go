@@ -0,0 +1,101 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/larksuite/botframework-go/SDK/appconfig"
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+func TestEventSignature(t *testing.T) {
+	appID := "cli_test_event_signature"
+	encryptKey := "event_encrypt_key"
+	appconfig.Init(appconfig.AppConfig{
+		AppID:       appID,
+		AppType:     protocol.InternalApp,
+		VerifyToken: testVerifyToken,
+		EncryptKey:  encryptKey,
+	})
+
+	called := 0
+	event.EventRegister(appID, protocol.EventTypeAddBot, func(ctx context.Context, eventBody []byte) error {
+		called++
+		return nil
+	})
+	event.SetEventSignatureCheck(appID, &event.SignatureOption{TimestampWindow: time.Minute})
+
+	ctx := context.Background()
+	body := encryptTestEvent(newTestEventBody(appID, "sig_1", protocol.EventTypeAddBot), encryptKey)
+	signHeader := func(timestamp int64, body string) map[string]string {
+		ts := strconv.FormatInt(timestamp, 10)
+		return map[string]string{
+			event.HeaderRequestTimestamp: ts,
+			event.HeaderRequestNonce:     "nonce",
+			event.HeaderSignature:        fmt.Sprintf("%x", sha256.Sum256([]byte(ts+"nonce"+encryptKey+body))),
+		}
+	}
+
+	// valid
+	_, err := event.EventCallbackWithHeader(ctx, body, appID, signHeader(time.Now().Unix(), body))
+	if err != nil || called != 1 {
+		t.Errorf("valid signature: called[%d]err[%v]", called, err)
+	}
+
+	// without header
+	_, err = event.EventCallback(ctx, body, appID)
+	if err == nil || !strings.Contains(err.Error(), common.ErrEventSignatureInvalid.String()) {
+		t.Errorf("without header: err[%v]", err)
+	}
+
+	// invalid signature
+	header := signHeader(time.Now().Unix(), body)
+	header[event.HeaderSignature] = "invalid"
+	_, err = event.EventCallbackWithHeader(ctx, body, appID, header)
+	if err == nil || !strings.Contains(err.Error(), common.ErrEventSignatureInvalid.String()) {
+		t.Errorf("invalid signature: err[%v]", err)
+	}
+
+	// stale timestamp
+	_, err = event.EventCallbackWithHeader(ctx, body, appID, signHeader(time.Now().Add(-time.Hour).Unix(), body))
+	if err == nil || !strings.Contains(err.Error(), common.ErrEventTimestampExpired.String()) {
+		t.Errorf("stale timestamp: err[%v]", err)
+	}
+
+	if called != 1 {
+		t.Errorf("handler should not be called: called[%d]", called)
+	}
+
+	// the timestamp window is not checked in the replay mode, the signature is checked unless the record is redacted
+	replayCtx := event.WithReplay(ctx, false)
+	_, err = event.EventCallbackWithHeader(replayCtx, body, appID, signHeader(time.Now().Add(-time.Hour).Unix(), body))
+	if err != nil || called != 2 {
+		t.Errorf("replay stale timestamp: called[%d]err[%v]", called, err)
+	}
+	_, err = event.EventCallbackWithHeader(replayCtx, body, appID, header)
+	if err == nil || !strings.Contains(err.Error(), common.ErrEventSignatureInvalid.String()) {
+		t.Errorf("replay invalid signature: err[%v]", err)
+	}
+	_, err = event.EventCallbackWithHeader(event.WithReplay(ctx, true), body, appID, header)
+	if err != nil || called != 3 {
+		t.Errorf("replay redacted: called[%d]err[%v]", called, err)
+	}
+
+	// disable
+	event.SetEventSignatureCheck(appID, nil)
+	_, err = event.EventCallback(ctx, encryptTestEvent(newTestEventBody(appID, "sig_2", protocol.EventTypeAddBot), encryptKey), appID)
+	if err != nil || called != 4 {
+		t.Errorf("disable signature check: called[%d]err[%v]", called, err)
+	}
+}
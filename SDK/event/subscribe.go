@@ -0,0 +1,139 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+)
+
+// FanOutPolicy how to run multiple handlers of one event type
+type FanOutPolicy int
+
+// The retry(see SetEventRetryPolicy) only re-runs the failed handler and the handlers not run yet,
+// the handlers succeeded in the previous attempts are skipped.
+const (
+	FanOutAll        FanOutPolicy = iota // run all handlers, return the errors of all failed handlers
+	FanOutFirstError                     // run handlers in order, stop at the first failed handler
+)
+
+const (
+	fanOutProgressContextKey = "botframework_fan_out_progress"
+)
+
+// fanOutProgress the handlers succeeded in the attempts of one event
+type fanOutProgress struct {
+	succeeded map[int]bool
+	mu        sync.Mutex
+}
+
+func (p *fanOutProgress) isSucceeded(i int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.succeeded[i]
+}
+
+func (p *fanOutProgress) setSucceeded(i int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.succeeded[i] = true
+}
+
+// withFanOutProgress the attempts of the event sharing the ctx skip the succeeded handlers
+func withFanOutProgress(ctx context.Context) context.Context {
+	return context.WithValue(ctx, fanOutProgressContextKey, &fanOutProgress{succeeded: make(map[int]bool)})
+}
+
+// EventSubscribe the handler is appended to the handlers registered before, demo:
+// event.EventSubscribe(appID, protocol.EventTypeAddBot, SendWelcomeMessage)
+// event.EventSubscribe(appID, protocol.EventTypeAddBot, SaveChatInfo)
+func EventSubscribe(appID, eventTypeList string, eventHandler EventHandler) error {
+	return defaultRouter.EventSubscribe(appID, eventTypeList, eventHandler)
+}
+
+// EventFallbackRegister the handler of the app for the unregistered event types. A nil handler removes the registered one.
+func EventFallbackRegister(appID string, eventHandler EventHandler) error {
+	return defaultRouter.EventFallbackRegister(appID, eventHandler)
+}
+
+// SetEventFanOutPolicy default FanOutAll
+func SetEventFanOutPolicy(appID string, policy FanOutPolicy) {
+	defaultRouter.SetEventFanOutPolicy(appID, policy)
+}
+
+// IgnoreUnregisteredEvent ack the unregistered event types silently, instead of returning ErrEventTypeUnregistered
+func IgnoreUnregisteredEvent(appID string, ignore bool) {
+	defaultRouter.IgnoreUnregisteredEvent(appID, ignore)
+}
+
+// EventSubscribe the handler is appended to the handlers registered before
+func (r *Router) EventSubscribe(appID, eventTypeList string, eventHandler EventHandler) error {
+	if appID == "" || eventTypeList == "" || eventHandler == nil {
+		return common.ErrEventTypeRegister.ErrorWithExtStr(
+			fmt.Sprintf("params is empty or nil. AppID[%s]EventType[%s]HandlerIsNil[%t]", appID, eventTypeList, eventHandler == nil))
+	}
+
+	r.eventManager.Add(appID, eventTypeList, eventHandler)
+
+	return nil
+}
+
+// EventFallbackRegister the handler of the app for the unregistered event types. A nil handler removes the registered one.
+func (r *Router) EventFallbackRegister(appID string, eventHandler EventHandler) error {
+	if appID == "" {
+		return common.ErrEventTypeRegister.ErrorWithExtStr("fallback appID is empty")
+	}
+
+	r.eventManager.SetFallback(appID, eventHandler)
+
+	return nil
+}
+
+// SetEventFanOutPolicy default FanOutAll
+func (r *Router) SetEventFanOutPolicy(appID string, policy FanOutPolicy) {
+	r.eventManager.SetPolicy(appID, policy)
+}
+
+// IgnoreUnregisteredEvent ack the unregistered event types silently, instead of returning ErrEventTypeUnregistered
+func (r *Router) IgnoreUnregisteredEvent(appID string, ignore bool) {
+	r.eventManager.SetIgnoreUnregistered(appID, ignore)
+}
+
+func fanOut(policy FanOutPolicy, handlers []EventHandler) EventHandler {
+	return func(ctx context.Context, eventBody []byte) error {
+		progress, _ := ctx.Value(fanOutProgressContextKey).(*fanOutProgress)
+
+		var errs []string
+		for i, handler := range handlers {
+			if progress != nil && progress.isSucceeded(i) {
+				continue
+			}
+
+			err := handler(ctx, eventBody)
+			if err == nil {
+				if progress != nil {
+					progress.setSucceeded(i)
+				}
+				continue
+			}
+
+			if policy == FanOutFirstError {
+				return fmt.Errorf("handler[%d] error[%v]", i, err)
+			}
+			errs = append(errs, fmt.Sprintf("handler[%d] error[%v]", i, err))
+		}
+
+		if len(errs) != 0 {
+			return fmt.Errorf("%s", strings.Join(errs, "; "))
+		}
+		return nil
+	}
+}
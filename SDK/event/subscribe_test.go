@@ -0,0 +1,84 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+func TestEventSubscribe(t *testing.T) {
+	appID := "cli_test_event_subscribe"
+	initTestApp(appID)
+
+	var trace []string
+	subscriber := func(name string, err error) event.EventHandler {
+		return func(ctx context.Context, eventBody []byte) error {
+			trace = append(trace, name)
+			return err
+		}
+	}
+	event.EventSubscribe(appID, protocol.EventTypeAddBot, subscriber("first", fmt.Errorf("first failed")))
+	event.EventSubscribe(appID, protocol.EventTypeAddBot, subscriber("second", nil))
+
+	ctx := context.Background()
+
+	// FanOutAll
+	_, err := event.EventCallback(ctx, newTestEventBody(appID, "sub_1", protocol.EventTypeAddBot), appID)
+	if err == nil || len(trace) != 2 {
+		t.Errorf("FanOutAll: trace[%v]err[%v]", trace, err)
+	}
+
+	// FanOutFirstError
+	trace = nil
+	event.SetEventFanOutPolicy(appID, event.FanOutFirstError)
+	_, err = event.EventCallback(ctx, newTestEventBody(appID, "sub_2", protocol.EventTypeAddBot), appID)
+	if err == nil || len(trace) != 1 {
+		t.Errorf("FanOutFirstError: trace[%v]err[%v]", trace, err)
+	}
+
+	// EventRegister replaces the subscribers
+	trace = nil
+	event.EventRegister(appID, protocol.EventTypeAddBot, subscriber("replaced", nil))
+	_, err = event.EventCallback(ctx, newTestEventBody(appID, "sub_3", protocol.EventTypeAddBot), appID)
+	if err != nil || len(trace) != 1 || trace[0] != "replaced" {
+		t.Errorf("EventRegister: trace[%v]err[%v]", trace, err)
+	}
+}
+
+func TestEventFallback(t *testing.T) {
+	appID := "cli_test_event_fallback"
+	initTestApp(appID)
+
+	ctx := context.Background()
+
+	// unregistered
+	_, err := event.EventCallback(ctx, newTestEventBody(appID, "fallback_1", protocol.EventTypeChatDisband), appID)
+	if err == nil {
+		t.Errorf("unregistered event type should fail")
+	}
+
+	// ignore unregistered
+	event.IgnoreUnregisteredEvent(appID, true)
+	_, err = event.EventCallback(ctx, newTestEventBody(appID, "fallback_2", protocol.EventTypeChatDisband), appID)
+	if err != nil {
+		t.Errorf("IgnoreUnregisteredEvent: err[%v]", err)
+	}
+
+	// fallback
+	var fallbackBody []byte
+	event.EventFallbackRegister(appID, func(ctx context.Context, eventBody []byte) error {
+		fallbackBody = eventBody
+		return nil
+	})
+	_, err = event.EventCallback(ctx, newTestEventBody(appID, "fallback_3", protocol.EventTypeChatDisband), appID)
+	if err != nil || len(fallbackBody) == 0 {
+		t.Errorf("EventFallbackRegister: body[%s]err[%v]", fallbackBody, err)
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+// CardTimeoutFallback the response of the card action callback when the handler times out
+type CardTimeoutFallback struct {
+	Card  *protocol.CardForm  // the card to update, nil means the card is not updated
+	Toast *protocol.ToastTips // the toast shown to the user, nil means no toast
+}
+
+func (f *CardTimeoutFallback) response() *protocol.CardForm {
+	card := &protocol.CardForm{}
+	if f.Card != nil {
+		c := *f.Card
+		card = &c
+	}
+	if f.Toast != nil {
+		card.Toast = f.Toast
+	}
+	return card
+}
+
+// TimeoutManager the timeouts of the handlers. The key "" of the second level is the default timeout of the app.
+type TimeoutManager struct {
+	event        map[string]map[string]time.Duration // appID => eventType => timeout
+	botMsg       map[string]map[string]time.Duration // appID => cmdName => timeout
+	action       map[string]map[string]time.Duration // appID => method => timeout
+	cardFallback map[string]*CardTimeoutFallback     // appID => fallback
+	rwMu         sync.RWMutex
+}
+
+func (m *TimeoutManager) set(timeouts map[string]map[string]time.Duration, appID string, names []string, timeout time.Duration) {
+	m.rwMu.Lock()
+	defer m.rwMu.Unlock()
+
+	if _, ok := timeouts[appID]; !ok {
+		timeouts[appID] = make(map[string]time.Duration, 0)
+	}
+
+	for _, name := range names {
+		if timeout <= 0 {
+			delete(timeouts[appID], name)
+		} else {
+			timeouts[appID][name] = timeout
+		}
+	}
+}
+
+// get the timeout of the name, or the default timeout of the app
+func (m *TimeoutManager) get(timeouts map[string]map[string]time.Duration, appID, name string) time.Duration {
+	m.rwMu.RLock()
+	defer m.rwMu.RUnlock()
+
+	if timeout, ok := timeouts[appID][name]; ok {
+		return timeout
+	}
+	return timeouts[appID][""]
+}
+
+func (m *TimeoutManager) SetCardFallback(appID string, fallback *CardTimeoutFallback) {
+	m.rwMu.Lock()
+	defer m.rwMu.Unlock()
+
+	if fallback == nil {
+		delete(m.cardFallback, appID)
+		return
+	}
+	m.cardFallback[appID] = fallback
+}
+
+func (m *TimeoutManager) GetCardFallback(appID string) *CardTimeoutFallback {
+	m.rwMu.RLock()
+	defer m.rwMu.RUnlock()
+
+	return m.cardFallback[appID]
+}
+
+// WrapEvent return the handler itself if no timeout is set
+func (m *TimeoutManager) WrapEvent(appID, eventType string, handler EventHandler) EventHandler {
+	timeout := m.get(m.event, appID, eventType)
+	if timeout <= 0 {
+		return handler
+	}
+
+	return func(ctx context.Context, eventBody []byte) error {
+		return runWithTimeout(ctx, timeout, common.ErrEventHandlerTimeout, func(ctx context.Context) error {
+			return handler(ctx, eventBody)
+		})
+	}
+}
+
+// WrapBotMsg return the handler itself if no timeout is set
+func (m *TimeoutManager) WrapBotMsg(appID, cmdName string, handler HandlerBotMsg) HandlerBotMsg {
+	timeout := m.get(m.botMsg, appID, strings.ToLower(cmdName))
+	if timeout <= 0 {
+		return handler
+	}
+
+	return func(ctx context.Context, msg *protocol.BotRecvMsg) error {
+		return runWithTimeout(ctx, timeout, common.ErrBotRecvMsgHandlerTimeout, func(ctx context.Context) error {
+			return handler(ctx, msg)
+		})
+	}
+}
+
+// WrapAction return the handler itself if no timeout is set.
+// The fallback of the app is returned when the handler times out.
+func (m *TimeoutManager) WrapAction(appID, method string, handler ActionMethod) ActionMethod {
+	timeout := m.get(m.action, appID, method)
+	if timeout <= 0 {
+		return handler
+	}
+
+	return func(ctx context.Context, cardCallback *protocol.CardCallbackForm) (*protocol.CardForm, error) {
+		var card *protocol.CardForm
+		err := runWithTimeout(ctx, timeout, common.ErrCardHandlerTimeout, func(ctx context.Context) error {
+			c, err := handler(ctx, cardCallback)
+			card = c
+			return err
+		})
+		if err == nil {
+			return card, nil
+		}
+
+		if fallback := m.GetCardFallback(appID); fallback != nil && isTimeout(err) {
+			common.Logger(ctx).Errorf("cardTimeout: return fallback, method[%s]error[%v]", method, err)
+			return fallback.response(), nil
+		}
+		return nil, err
+	}
+}
+
+var handlerTimeout *TimeoutManager
+
+func init() {
+	handlerTimeout = &TimeoutManager{
+		event:        make(map[string]map[string]time.Duration, 0),
+		botMsg:       make(map[string]map[string]time.Duration, 0),
+		action:       make(map[string]map[string]time.Duration, 0),
+		cardFallback: make(map[string]*CardTimeoutFallback, 0),
+	}
+}
+
+// SetEventTimeout demo:
+// event.SetEventTimeout(appID, "", 10*time.Second)                        // all event types of the app
+// event.SetEventTimeout(appID, protocol.EventTypeMessage, 3*time.Second) // the event types, separated by comma
+//
+// The ctx of the handler is canceled after the timeout, and ErrEventHandlerTimeout is returned.
+// The handler is not stopped by the timeout, it keeps running in background until it returns, so it must watch ctx.Done().
+// The timed out event is not retried unless RetryPolicy.RetryTimeout is set.
+// The timeout of the event type has priority over the default one of the app. A timeout <= 0 removes the timeout.
+func SetEventTimeout(appID, eventTypeList string, timeout time.Duration) error {
+	if appID == "" {
+		return common.ErrEventTypeRegister.ErrorWithExtStr("timeout appID is empty")
+	}
+
+	handlerTimeout.set(handlerTimeout.event, appID, splitEventTypeList(eventTypeList), timeout)
+	return nil
+}
+
+// SetBotMsgTimeout the timeout of the bot command handler, cmdName "" means all commands of the app, see SetEventTimeout
+func SetBotMsgTimeout(appID, cmdName string, timeout time.Duration) error {
+	if appID == "" {
+		return common.ErrBotRecvMsgRegister.ErrorWithExtStr("timeout appID is empty")
+	}
+
+	handlerTimeout.set(handlerTimeout.botMsg, appID, []string{strings.ToLower(cmdName)}, timeout)
+	return nil
+}
+
+// SetCardTimeout the timeout of the card action handler, method "" means all methods of the app, see SetEventTimeout
+func SetCardTimeout(appID, method string, timeout time.Duration) error {
+	if appID == "" {
+		return common.ErrCardMethodRegister.ErrorWithExtStr("timeout appID is empty")
+	}
+
+	handlerTimeout.set(handlerTimeout.action, appID, []string{method}, timeout)
+	return nil
+}
+
+// SetCardTimeoutFallback the response instead of ErrCardHandlerTimeout when the card action handler times out, demo:
+// event.SetCardTimeoutFallback(appID, &event.CardTimeoutFallback{
+// 	Toast: &protocol.ToastTips{Content: "busy, please try again later"},
+// })
+//
+// A nil fallback removes the fallback.
+func SetCardTimeoutFallback(appID string, fallback *CardTimeoutFallback) error {
+	if appID == "" {
+		return common.ErrCardMethodRegister.ErrorWithExtStr("timeout fallback appID is empty")
+	}
+
+	handlerTimeout.SetCardFallback(appID, fallback)
+	return nil
+}
+
+// runWithTimeout the handler keeps running in background after the timeout, it should return when ctx is done.
+// The cancellation of the parent ctx is returned as ctx.Err() instead of the timeout error.
+func runWithTimeout(ctx context.Context, timeout time.Duration, errTimeout *common.ErrCodeMsg, handler func(ctx context.Context) error) error {
+	parent := ctx
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		defer func() {
+			if r := recover(); r != nil {
+				done <- recoveredError(ctx, r)
+			}
+		}()
+
+		done <- handler(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if err := parent.Err(); err != nil {
+			return err
+		}
+		return timeoutError{
+			error:    errTimeout.ErrorWithExtStr(fmt.Sprintf("timeout[%v] error[%v]", timeout, ctx.Err())),
+			finished: finished,
+		}
+	}
+}
+
+type timeoutError struct {
+	error
+	finished <-chan struct{} // closed when the abandoned handler returns
+}
+
+func isTimeout(err error) bool {
+	return errors.As(err, &timeoutError{})
+}
+
+// waitTimedOut wait for the abandoned handler of the timeout error, return nil at once if err is not a timeout
+func waitTimedOut(ctx context.Context, err error) error {
+	var errTimeout timeoutError
+	if !errors.As(err, &errTimeout) {
+		return nil
+	}
+
+	select {
+	case <-errTimeout.finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
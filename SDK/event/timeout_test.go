@@ -0,0 +1,155 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event_test
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+func TestEventTimeout(t *testing.T) {
+	appID := "cli_test_event_timeout"
+	initTestApp(appID)
+
+	slow := func(ctx context.Context, eventBody []byte) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+			return nil
+		}
+	}
+	event.EventRegister(appID, "add_bot,remove_bot", slow)
+	event.SetEventTimeout(appID, "", 20*time.Millisecond)
+	event.SetEventTimeout(appID, protocol.EventTypeRemoveBot, time.Second)
+	defer event.SetEventTimeout(appID, "", 0)
+
+	ctx := context.Background()
+	start := time.Now()
+	_, err := event.EventCallback(ctx, newTestEventBody(appID, "timeout_1", protocol.EventTypeAddBot), appID)
+	if err == nil || !strings.Contains(err.Error(), common.ErrEventHandlerTimeout.String()) ||
+		strings.Contains(err.Error(), common.ErrEventHandlerFailed.String()) {
+		t.Errorf("timeout: err[%v]", err)
+	}
+	if cost := time.Since(start); cost > 80*time.Millisecond {
+		t.Errorf("timeout: cost[%v]", cost)
+	}
+
+	// the timeout of the event type has priority
+	_, err = event.EventCallback(ctx, newTestEventBody(appID, "timeout_2", protocol.EventTypeRemoveBot), appID)
+	if err != nil {
+		t.Errorf("event type timeout: err[%v]", err)
+	}
+}
+
+func TestCardTimeoutFallback(t *testing.T) {
+	appID := "cli_test_card_timeout"
+	initTestApp(appID)
+	event.CardRegister(appID, "timeout_click", func(ctx context.Context, cardCallback *protocol.CardCallbackForm) (*protocol.CardForm, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	event.SetCardTimeout(appID, "timeout_click", 20*time.Millisecond)
+
+	ctx := context.Background()
+	body := []byte(`{"open_id":"ou_123","action":{"value":{"method":"timeout_click","sid":"1"}}}`)
+	header := map[string]string{
+		event.HeaderRequestTimestamp: "1577808000",
+		event.HeaderRequestNonce:     "nonce",
+		event.HeaderSignature:        fmt.Sprintf("%x", sha1.Sum([]byte("1577808000"+"nonce"+testVerifyToken+string(body)))),
+	}
+
+	_, _, err := event.CardCallBack(ctx, appID, header, body)
+	if err == nil || !strings.Contains(err.Error(), common.ErrCardHandlerTimeout.String()) {
+		t.Errorf("timeout: err[%v]", err)
+	}
+
+	event.SetCardTimeoutFallback(appID, &event.CardTimeoutFallback{Toast: &protocol.ToastTips{Content: "busy"}})
+	card, _, err := event.CardCallBack(ctx, appID, header, body)
+	if err != nil || card == nil || card.Toast == nil || card.Toast.Content != "busy" {
+		t.Errorf("fallback: card[%+v]err[%v]", card, err)
+	}
+}
+
+func TestBotMsgTimeout(t *testing.T) {
+	appID := "cli_test_bot_msg_timeout"
+	initTestApp(appID)
+	event.BotRecvMsgRegister(appID, "slow", func(ctx context.Context, msg *protocol.BotRecvMsg) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	event.SetBotMsgTimeout(appID, "SLOW", 20*time.Millisecond)
+
+	data := fmt.Sprintf(`{"type":"message","app_id":"%s","msg_type":"text","text_without_at_bot":"slow"}`, appID)
+	err := event.BotRecvMsgHandler(context.Background(), []byte(data))
+	if err == nil || !strings.Contains(err.Error(), common.ErrBotRecvMsgHandlerTimeout.String()) {
+		t.Errorf("timeout: err[%v]", err)
+	}
+}
+
+func TestEventTimeoutCanceled(t *testing.T) {
+	appID := "cli_test_event_timeout_canceled"
+	initTestApp(appID)
+	event.EventRegister(appID, protocol.EventTypeAddBot, func(ctx context.Context, eventBody []byte) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	event.SetEventTimeout(appID, "", time.Second)
+
+	// the cancellation of the caller is not reported as the timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	_, err := event.EventCallback(ctx, newTestEventBody(appID, "timeout_canceled", protocol.EventTypeAddBot), appID)
+	if err == nil || strings.Contains(err.Error(), common.ErrEventHandlerTimeout.String()) ||
+		!strings.Contains(err.Error(), context.Canceled.Error()) {
+		t.Errorf("canceled: err[%v]", err)
+	}
+}
+
+func TestEventTimeoutRetry(t *testing.T) {
+	appID := "cli_test_event_timeout_retry"
+	initTestApp(appID)
+
+	// the handler ignores ctx and keeps running after the timeout
+	var called, running, overlapped int32
+	event.EventRegister(appID, protocol.EventTypeAddBot, func(ctx context.Context, eventBody []byte) error {
+		atomic.AddInt32(&called, 1)
+		if atomic.AddInt32(&running, 1) > 1 {
+			atomic.StoreInt32(&overlapped, 1)
+		}
+		defer atomic.AddInt32(&running, -1)
+
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	})
+	event.SetEventTimeout(appID, "", 10*time.Millisecond)
+	event.SetEventRetryPolicy(appID, &event.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+	defer event.SetEventRetryPolicy(appID, nil)
+
+	// the timeout is not retried by default
+	ctx := context.Background()
+	_, err := event.EventCallback(ctx, newTestEventBody(appID, "timeout_retry_1", protocol.EventTypeAddBot), appID)
+	if err == nil || !strings.Contains(err.Error(), common.ErrEventHandlerTimeout.String()) || atomic.LoadInt32(&called) != 1 {
+		t.Errorf("no retry: called[%d]err[%v]", atomic.LoadInt32(&called), err)
+	}
+	time.Sleep(60 * time.Millisecond)
+
+	// the retry waits for the timed out handler, the attempts don't overlap
+	atomic.StoreInt32(&called, 0)
+	event.SetEventRetryPolicy(appID, &event.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, RetryTimeout: true})
+	_, err = event.EventCallback(ctx, newTestEventBody(appID, "timeout_retry_2", protocol.EventTypeAddBot), appID)
+	if err == nil || atomic.LoadInt32(&called) != 3 || atomic.LoadInt32(&overlapped) != 0 {
+		t.Errorf("retry timeout: called[%d]overlapped[%d]err[%v]", atomic.LoadInt32(&called), atomic.LoadInt32(&overlapped), err)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+)
+
+const (
+	rawEventContextKey = "botframework_raw_event"
+)
+
+// On register a handler receiving the decoded event, demo:
+//
+// event.On(appID, protocol.EventTypeAddBot, func(ctx context.Context, e *protocol.AddBotEvent) error {
+// 	// e.OpenChatID ...
+// 	// raw := event.RawEventFromContext(ctx), for the fields not modeled by protocol.AddBotEvent
+// 	return nil
+// })
+func On[T any](appID, eventTypeList string, handler func(ctx context.Context, event *T) error) error {
+	return OnWithRouter(defaultRouter, appID, eventTypeList, handler)
+}
+
+// OnWithRouter the same as On, but register the handler to the router
+func OnWithRouter[T any](r *Router, appID, eventTypeList string, handler func(ctx context.Context, event *T) error) error {
+	if handler == nil {
+		return common.ErrEventTypeRegister.ErrorWithExtStr("typed handler is nil")
+	}
+
+	return r.EventRegister(appID, eventTypeList, func(ctx context.Context, eventBody []byte) error {
+		event := new(T)
+		err := json.Unmarshal(eventBody, event)
+		if err != nil {
+			return common.ErrJsonUnmarshal.ErrorWithExtErr(err)
+		}
+
+		return handler(context.WithValue(ctx, rawEventContextKey, eventBody), event)
+	})
+}
+
+// RawEventFromContext get the raw event body in the handler registered by On
+func RawEventFromContext(ctx context.Context) []byte {
+	raw, ok := ctx.Value(rawEventContextKey).([]byte)
+	if !ok {
+		return nil
+	}
+	return raw
+}
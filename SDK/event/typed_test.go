@@ -0,0 +1,57 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package event_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bitly/go-simplejson"
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+func TestTypedEventRegister(t *testing.T) {
+	appID := "cli_test_typed_event"
+	initTestApp(appID)
+
+	var got *protocol.AddBotEvent
+	var extra string
+	err := event.On(appID, protocol.EventTypeAddBot, func(ctx context.Context, e *protocol.AddBotEvent) error {
+		got = e
+
+		raw, _ := simplejson.NewJson(event.RawEventFromContext(ctx))
+		extra, _ = raw.Get("extra_field").String()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("On failed: err[%v]", err)
+	}
+
+	ctx := context.Background()
+	body := fmt.Sprintf(`{"uuid":"typed_1","token":"%s","type":"event_callback","event":{"type":"add_bot","app_id":"%s","open_chat_id":"oc_typed","chat_name":"typed","extra_field":"extra"}}`,
+		testVerifyToken, appID)
+	_, err = event.EventCallback(ctx, body, appID)
+	if err != nil {
+		t.Fatalf("EventCallback failed: err[%v]", err)
+	}
+	if got == nil || got.OpenChatID != "oc_typed" || got.ChatName != "typed" || got.AppID != appID {
+		t.Errorf("typed event: got[%+v]", got)
+	}
+	if extra != "extra" {
+		t.Errorf("raw event: extra[%s]", extra)
+	}
+
+	// decode error
+	body = fmt.Sprintf(`{"uuid":"typed_2","token":"%s","type":"event_callback","event":{"type":"add_bot","app_id":"%s","owner_is_bot":"invalid"}}`,
+		testVerifyToken, appID)
+	_, err = event.EventCallback(ctx, body, appID)
+	if err == nil || !strings.Contains(err.Error(), common.ErrJsonUnmarshal.String()) {
+		t.Errorf("decode error: err[%v]", err)
+	}
+}
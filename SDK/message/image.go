@@ -97,7 +97,7 @@ func GetImageBinData(ctx context.Context, tenantKey, appID, imageKey string) ([]
 		return nil, err
 	}
 
-	rspBytes, httpCode, err := common.DoHttpGetOApi(protocol.GetImagePath,
+	rspBytes, httpCode, err := common.DoHttpGetOApiWithContext(ctx, protocol.GetImagePath,
 		map[string]string{"Authorization": fmt.Sprintf("Bearer %s", accessToken)},
 		map[string]string{"image_key": imageKey},
 	)
@@ -121,7 +121,7 @@ func UploadImage(ctx context.Context, tenantKey, appID string, body *bytes.Buffe
 	header := map[string]string{"Authorization": authorization, "Content-Type": contentType}
 
 	reqURL := common.GetOpenPlatformHost() + string(protocol.UploadImagePath)
-	rspBytes, _, err := common.DoHttp(common.HTTPMethodPost, reqURL, header, body)
+	rspBytes, _, err := common.DoHttpWithContext(ctx, common.HTTPMethodPost, reqURL, header, body)
 	if err != nil {
 		return nil, common.ErrOpenApiFailed.ErrorWithExtErr(err)
 	}
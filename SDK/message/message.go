@@ -120,7 +120,7 @@ func UpdateCard(ctx context.Context, tenantKey, appID string, token string, card
 		Card:  card,
 	}
 
-	rspBytes, statusCode, err := common.DoHttpPostOApi(protocol.CardUpdatePath, common.NewHeaderToken(accessToken), request)
+	rspBytes, statusCode, err := common.DoHttpPostOApiWithContext(ctx, protocol.CardUpdatePath, common.NewHeaderToken(accessToken), request)
 	if err != nil {
 		return nil, common.ErrOpenApiFailed.ErrorWithExtErr(err)
 	}
@@ -249,7 +249,7 @@ func sendMsg(ctx context.Context,
 		}
 	}
 
-	rspBytes, statusCode, err := common.DoHttpPostOApi(protocol.SendMessagePath, common.NewHeaderToken(accessToken), request)
+	rspBytes, statusCode, err := common.DoHttpPostOApiWithContext(ctx, protocol.SendMessagePath, common.NewHeaderToken(accessToken), request)
 	if err != nil {
 		return nil, common.ErrOpenApiFailed.ErrorWithExtErr(err)
 	}
@@ -289,7 +289,7 @@ func sendCardMsg(ctx context.Context,
 		}
 	}
 
-	rspBytes, statusCode, err := common.DoHttpPostOApi(protocol.SendMessagePath, common.NewHeaderToken(accessToken), request)
+	rspBytes, statusCode, err := common.DoHttpPostOApiWithContext(ctx, protocol.SendMessagePath, common.NewHeaderToken(accessToken), request)
 	if err != nil {
 		return nil, common.ErrOpenApiFailed.ErrorWithExtErr(err)
 	}
@@ -322,7 +322,7 @@ func sendMsgBatch(ctx context.Context,
 		return nil, err
 	}
 
-	rspBytes, statusCode, err := common.DoHttpPostOApi(protocol.SendMessageBatchPath, common.NewHeaderToken(accessToken), request)
+	rspBytes, statusCode, err := common.DoHttpPostOApiWithContext(ctx, protocol.SendMessageBatchPath, common.NewHeaderToken(accessToken), request)
 	if err != nil {
 		return nil, common.ErrOpenApiFailed.ErrorWithExtErr(err)
 	}
@@ -355,7 +355,7 @@ func sendCardMsgBatch(ctx context.Context,
 		return nil, err
 	}
 
-	rspBytes, statusCode, err := common.DoHttpPostOApi(protocol.SendMessageBatchPath, common.NewHeaderToken(accessToken), request)
+	rspBytes, statusCode, err := common.DoHttpPostOApiWithContext(ctx, protocol.SendMessageBatchPath, common.NewHeaderToken(accessToken), request)
 	if err != nil {
 		return nil, common.ErrOpenApiFailed.ErrorWithExtErr(err)
 	}
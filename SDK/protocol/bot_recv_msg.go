@@ -7,6 +7,7 @@ package protocol
 const (
 	CmdDefault  = "default"
 	DescDefault = "defalut cmd"
+	CmdHelp     = "help"
 )
 
 type BotRecvMsg struct {
@@ -21,4 +22,6 @@ type BotRecvMsg struct {
 	OpenID        string
 	OpenMessageID string
 	OriData       interface{}
+	RichText      *RichTextForm     // the content of the post message, including the bot mention
+	MatchGroups   map[string]string // the named groups of the regexp matcher(event.MessageMatcher)
 }
@@ -364,6 +364,7 @@ type CardForm struct {
 	Header       *CardHeaderForm          `json:"header,omitempty" validate:"omitempty"`
 	Elements     []interface{}            `json:"elements,omitempty" validate:"omitempty"`
 	I18NElements map[string][]interface{} `json:"i18n_elements,omitempty" validate:"omitempty"`
+	Toast        *ToastTips               `json:"toast,omitempty" validate:"omitempty"` // only used in the response of card action callback
 }
 
 type ToastTips struct {
@@ -47,6 +47,12 @@ const (
 	EventMsgTypeMergeForward = "merge_forward" // merge forward
 )
 
+const (
+	// event schema
+	EventSchemaV1 = "1.0"
+	EventSchemaV2 = "2.0"
+)
+
 // common field
 type CallbackBase struct {
 	Challenge string `json:"challenge"`
@@ -54,6 +60,42 @@ type CallbackBase struct {
 	Type      string `json:"type"`
 	Ts        string `json:"ts"`
 	Uuid      string `json:"uuid"`
+
+	// schema 2.0
+	Schema string         `json:"schema"`
+	Header *EventHeaderV2 `json:"header"`
+}
+
+func (c *CallbackBase) IsSchemaV2() bool {
+	return c.Schema == EventSchemaV2 && c.Header != nil
+}
+
+// GetToken v1: token, v2: header.token
+func (c *CallbackBase) GetToken() string {
+	if c.IsSchemaV2() {
+		return c.Header.Token
+	}
+	return c.Token
+}
+
+// schema 2.0 event header
+type EventHeaderV2 struct {
+	EventID    string `json:"event_id"`
+	EventType  string `json:"event_type"`
+	CreateTime string `json:"create_time"`
+	Token      string `json:"token"`
+	AppID      string `json:"app_id"`
+	TenantKey  string `json:"tenant_key"`
+}
+
+// EventHeader normalized event header of schema 1.0 and 2.0
+type EventHeader struct {
+	Schema     string // EventSchemaV1/EventSchemaV2
+	EventID    string // v1: uuid, v2: header.event_id
+	EventType  string // v1: event.type, v2: header.event_type
+	AppID      string // v1: event.app_id, v2: header.app_id
+	TenantKey  string // v1: event.tenant_key, v2: header.tenant_key
+	CreateTime string // v1: ts, v2: header.create_time
 }
 
 type BaseEvent struct {
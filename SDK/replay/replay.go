@@ -0,0 +1,213 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+// Package replay send the callbacks recorded by event.FileRecorder again,
+// to a running server by http, or to the registered handlers in-process.
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/event"
+)
+
+// Target receive the replayed record
+type Target interface {
+	Send(ctx context.Context, record *event.Record) error
+}
+
+type Option struct {
+	Speed      float64  // 1 keeps the intervals of the recording, 2 is twice as fast, 0 sends without waiting
+	EventTypes []string // only replay the events of the types, the card callbacks are skipped. Empty means all records
+	AppID      string   // only replay the records of the app. Empty means all apps
+}
+
+type Result struct {
+	Total   int // the number of records
+	Sent    int
+	Failed  int
+	Skipped int
+}
+
+// Load read the records of the json lines file
+func Load(path string) ([]*event.Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records := make([]*event.Record, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10<<20)
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		record := &event.Record{}
+		err = json.Unmarshal(data, record)
+		if err != nil {
+			return nil, common.ErrJsonUnmarshal.ErrorWithExtStr(fmt.Sprintf("line[%d] error[%v]", line, err))
+		}
+		records = append(records, record)
+	}
+
+	return records, scanner.Err()
+}
+
+// Replay send the records to the target in order. The errors of the target are logged, and counted in Result.Failed.
+// Return error if ctx is done before all records are sent.
+func Replay(ctx context.Context, records []*event.Record, target Target, option *Option) (*Result, error) {
+	if option == nil {
+		option = &Option{}
+	}
+	types := make(map[string]bool, len(option.EventTypes))
+	for _, t := range option.EventTypes {
+		types[t] = true
+	}
+
+	result := &Result{Total: len(records)}
+	var last time.Time
+	for _, record := range records {
+		if !match(record, option, types) {
+			result.Skipped++
+			continue
+		}
+
+		if option.Speed > 0 && !last.IsZero() && record.Time.After(last) {
+			wait := time.Duration(float64(record.Time.Sub(last)) / option.Speed)
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return result, ctx.Err()
+			}
+		}
+		last = record.Time
+
+		err := target.Send(ctx, record)
+		if err != nil {
+			result.Failed++
+			common.Logger(ctx).Errorf("replay: sendError[%v]kind[%s]appid[%s]eventType[%s]method[%s]time[%v]",
+				err, record.Kind, record.AppID, record.EventType, record.Method, record.Time)
+			continue
+		}
+		result.Sent++
+	}
+
+	return result, nil
+}
+
+// ParseEventTypes split the event types separated by comma, eg: "message, add_bot"
+func ParseEventTypes(s string) []string {
+	types := make([]string, 0)
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+func match(record *event.Record, option *Option, types map[string]bool) bool {
+	if option.AppID != "" && record.AppID != option.AppID {
+		return false
+	}
+	if len(types) != 0 && (record.Kind != event.RecordKindEvent || !types[record.EventType]) {
+		return false
+	}
+	return true
+}
+
+// HTTPTarget post the records to the callback urls of a running server, with the header event.HeaderReplay.
+// The server must trust the header(see webhook.Option.TrustReplayHeader), otherwise the old records are rejected
+// by the signature timestamp window and dropped by the dedup. Only trust it on the local server, eg: in development.
+type HTTPTarget struct {
+	EventURL string
+	CardURL  string
+	Client   *http.Client
+}
+
+func NewHTTPTarget(eventURL, cardURL string) *HTTPTarget {
+	return &HTTPTarget{
+		EventURL: eventURL,
+		CardURL:  cardURL,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (t *HTTPTarget) Send(ctx context.Context, record *event.Record) error {
+	url := t.EventURL
+	if record.Kind == event.RecordKindCard {
+		url = t.CardURL
+	}
+	if url == "" {
+		return fmt.Errorf("url of kind[%s] is empty", record.Kind)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBufferString(record.Body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	for k, v := range record.Header {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set(event.HeaderReplay, event.ReplayValue)
+	if record.Redacted {
+		req.Header.Set(event.HeaderReplay, event.ReplayValueRedacted)
+	}
+
+	rsp, err := t.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	body, _ := ioutil.ReadAll(rsp.Body)
+	if rsp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status[%d] body[%s]", rsp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// RouterTarget call the handlers registered in the router directly, nil router means the default router.
+// The records are sent in the replay mode, see event.WithReplay: they are not dropped as the duplicate or stale callbacks,
+// and the signature of the redacted record is not checked.
+type RouterTarget struct {
+	Router *event.Router
+}
+
+func NewRouterTarget(router *event.Router) *RouterTarget {
+	if router == nil {
+		router = event.DefaultRouter()
+	}
+	return &RouterTarget{Router: router}
+}
+
+func (t *RouterTarget) Send(ctx context.Context, record *event.Record) error {
+	ctx = event.WithReplay(ctx, record.Redacted)
+	switch record.Kind {
+	case event.RecordKindEvent:
+		_, err := t.Router.EventCallbackWithHeader(ctx, record.Body, record.AppID, record.Header)
+		return err
+	case event.RecordKindCard:
+		_, _, err := t.Router.CardCallBack(ctx, record.AppID, record.Header, []byte(record.Body))
+		return err
+	default:
+		return fmt.Errorf("unknown kind[%s]", record.Kind)
+	}
+}
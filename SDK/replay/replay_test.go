@@ -0,0 +1,171 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package replay_test
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/larksuite/botframework-go/SDK/appconfig"
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+	"github.com/larksuite/botframework-go/SDK/replay"
+	"github.com/larksuite/botframework-go/SDK/webhook"
+)
+
+const (
+	testAppID       = "cli_replay_test"
+	testVerifyToken = "replay_verify_token"
+)
+
+func init() {
+	appconfig.Init(appconfig.AppConfig{
+		AppID:       testAppID,
+		AppType:     protocol.InternalApp,
+		VerifyToken: testVerifyToken,
+	})
+}
+
+func newEventBody(uuid, eventType string) string {
+	return fmt.Sprintf(`{"uuid":"%s","token":"%s","type":"event_callback","event":{"type":"%s","app_id":"%s"}}`,
+		uuid, testVerifyToken, eventType, testAppID)
+}
+
+func record(t *testing.T) []*event.Record {
+	path := filepath.Join(t.TempDir(), "callback.jsonl")
+	recorder, err := event.NewFileRecorder(path)
+	if err != nil {
+		t.Fatalf("new recorder: err[%v]", err)
+	}
+
+	router := event.NewRouter()
+	router.EventRegister(testAppID, "add_bot,remove_bot", func(ctx context.Context, eventBody []byte) error { return nil })
+
+	event.SetRecorder(recorder)
+	ctx := context.Background()
+	router.EventCallback(ctx, newEventBody("1", protocol.EventTypeAddBot), testAppID)
+	time.Sleep(20 * time.Millisecond)
+	router.EventCallback(ctx, newEventBody("2", protocol.EventTypeRemoveBot), testAppID)
+	router.EventCallback(ctx, newEventBody("3", protocol.EventTypeAddBot), testAppID)
+	event.SetRecorder(nil)
+	recorder.Close()
+
+	records, err := replay.Load(path)
+	if err != nil || len(records) != 3 {
+		t.Fatalf("load: records[%d]err[%v]", len(records), err)
+	}
+	return records
+}
+
+func TestReplayRouter(t *testing.T) {
+	records := record(t)
+
+	called := map[string]int{}
+	router := event.NewRouter()
+	router.EventRegister(testAppID, "add_bot,remove_bot", func(ctx context.Context, eventBody []byte) error {
+		called[event.EventHeaderFromContext(ctx).EventType]++
+		return nil
+	})
+
+	// filter and speed
+	start := time.Now()
+	result, err := replay.Replay(context.Background(), records, replay.NewRouterTarget(router), &replay.Option{
+		Speed:      2,
+		EventTypes: []string{protocol.EventTypeAddBot},
+	})
+	if err != nil || result.Sent != 2 || result.Skipped != 1 || called[protocol.EventTypeAddBot] != 2 || called[protocol.EventTypeRemoveBot] != 0 {
+		t.Errorf("replay: result[%+v]called[%v]err[%v]", result, called, err)
+	}
+	if cost := time.Since(start); cost < 10*time.Millisecond {
+		t.Errorf("speed: cost[%v]", cost)
+	}
+}
+
+func TestReplayHTTP(t *testing.T) {
+	records := record(t)
+
+	called := 0
+	event.DefaultRouter().EventRegister(testAppID, protocol.EventTypeAddBot, func(ctx context.Context, eventBody []byte) error {
+		called++
+		return nil
+	})
+	server := httptest.NewServer(webhook.NewEventHandler(webhook.StaticAppID(testAppID), nil))
+	defer server.Close()
+
+	result, err := replay.Replay(context.Background(), records, replay.NewHTTPTarget(server.URL, ""), nil)
+	// remove_bot is not registered in the default router
+	if err != nil || result.Sent != 2 || result.Failed != 1 || called != 2 {
+		t.Errorf("replay: result[%+v]called[%d]err[%v]", result, called, err)
+	}
+}
+
+func TestReplayDedup(t *testing.T) {
+	// the recorded events are claimed by the dedup
+	event.EnableEventDedup(common.NewMemoryDBClient(), time.Hour)
+	defer event.DisableEventDedup()
+	records := record(t)
+
+	called := 0
+	router := event.NewRouter()
+	router.EventRegister(testAppID, "add_bot,remove_bot", func(ctx context.Context, eventBody []byte) error {
+		called++
+		return nil
+	})
+
+	// the replayed events are dispatched again, each time
+	for i := 1; i <= 2; i++ {
+		result, err := replay.Replay(context.Background(), records, replay.NewRouterTarget(router), nil)
+		if err != nil || result.Sent != 3 || called != 3*i {
+			t.Errorf("replay[%d]: result[%+v]called[%d]err[%v]", i, result, called, err)
+		}
+	}
+
+	// the duplicate delivery is still dropped
+	called = 0
+	router.EventCallback(context.Background(), records[0].Body, testAppID)
+	if called != 0 {
+		t.Errorf("duplicate delivery: called[%d]", called)
+	}
+}
+
+func TestParseEventTypes(t *testing.T) {
+	types := replay.ParseEventTypes(" message, ,add_bot,")
+	if len(types) != 2 || types[0] != "message" || types[1] != "add_bot" {
+		t.Errorf("types: %v", types)
+	}
+}
+
+func TestReplayHTTPTrusted(t *testing.T) {
+	event.EnableEventDedup(common.NewMemoryDBClient(), time.Hour)
+	defer event.DisableEventDedup()
+	records := record(t)
+
+	called := 0
+	event.EventReplace(testAppID, protocol.EventTypeAddBot, func(ctx context.Context, eventBody []byte) error {
+		called++
+		return nil
+	})
+
+	// the server not trusting the replay header drops the recorded events by the dedup
+	server := httptest.NewServer(webhook.NewEventHandler(webhook.StaticAppID(testAppID), nil))
+	defer server.Close()
+	result, err := replay.Replay(context.Background(), records, replay.NewHTTPTarget(server.URL, ""), nil)
+	if err != nil || result.Sent != 3 || called != 0 {
+		t.Errorf("untrusted: result[%+v]called[%d]err[%v]", result, called, err)
+	}
+
+	// remove_bot is not registered in the default router
+	trusted := httptest.NewServer(webhook.NewEventHandler(webhook.StaticAppID(testAppID), &webhook.Option{TrustReplayHeader: true}))
+	defer trusted.Close()
+	result, err = replay.Replay(context.Background(), records, replay.NewHTTPTarget(trusted.URL, ""), nil)
+	if err != nil || result.Sent != 2 || result.Failed != 1 || called != 2 {
+		t.Errorf("trusted: result[%+v]called[%d]err[%v]", result, called, err)
+	}
+}
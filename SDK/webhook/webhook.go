@@ -0,0 +1,275 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/event"
+)
+
+const (
+	defaultMaxBodySize = 10 << 20 // 10MB
+)
+
+// AppIDResolver get the app id which the request belongs to
+type AppIDResolver func(r *http.Request) (string, error)
+
+// ErrorEncoder write the error response
+type ErrorEncoder func(w http.ResponseWriter, r *http.Request, err error)
+
+// ResponseEncoder write the success response, data is the json body
+type ResponseEncoder func(w http.ResponseWriter, r *http.Request, data interface{})
+
+type Option struct {
+	MaxBodySize     int64
+	ErrorEncoder    ErrorEncoder
+	ResponseEncoder ResponseEncoder
+
+	// TrustReplayHeader handle the request with the header event.HeaderReplay in the replay mode(see event.WithReplay),
+	// eg: sent by replay.HTTPTarget. It bypasses the dedup and the replay protection,
+	// NEVER enable it on the server which can be reached by others.
+	TrustReplayHeader bool
+}
+
+func DefaultOption() *Option {
+	return &Option{
+		MaxBodySize:     defaultMaxBodySize,
+		ErrorEncoder:    DefaultErrorEncoder,
+		ResponseEncoder: DefaultResponseEncoder,
+	}
+}
+
+// DefaultErrorEncoder response 500 {"codemsg": "..."}, the same as the generated gin code
+func DefaultErrorEncoder(w http.ResponseWriter, r *http.Request, err error) {
+	writeJson(w, http.StatusInternalServerError, map[string]string{"codemsg": fmt.Sprintf("%v", err)})
+}
+
+// DefaultResponseEncoder response 200 with json body
+func DefaultResponseEncoder(w http.ResponseWriter, r *http.Request, data interface{}) {
+	writeJson(w, http.StatusOK, data)
+}
+
+// StaticAppID all requests belong to one app
+func StaticAppID(appID string) AppIDResolver {
+	return func(r *http.Request) (string, error) {
+		return appID, nil
+	}
+}
+
+// AppIDFromQuery demo: /webhook/event?appid=cli_12345 ==> AppIDFromQuery("appid")
+func AppIDFromQuery(key string) AppIDResolver {
+	return func(r *http.Request) (string, error) {
+		appID := r.URL.Query().Get(key)
+		if appID == "" {
+			return "", fmt.Errorf("query[%s] is empty", key)
+		}
+		return appID, nil
+	}
+}
+
+// AppIDFromHeader get app id from request header
+func AppIDFromHeader(key string) AppIDResolver {
+	return func(r *http.Request) (string, error) {
+		appID := r.Header.Get(key)
+		if appID == "" {
+			return "", fmt.Errorf("header[%s] is empty", key)
+		}
+		return appID, nil
+	}
+}
+
+// AppIDFromPathSuffix demo: /webhook/event/cli_12345 ==> AppIDFromPathSuffix("/webhook/event/")
+func AppIDFromPathSuffix(prefix string) AppIDResolver {
+	return func(r *http.Request) (string, error) {
+		if !strings.HasPrefix(r.URL.Path, prefix) {
+			return "", fmt.Errorf("path[%s] without prefix[%s]", r.URL.Path, prefix)
+		}
+
+		appID := strings.Trim(r.URL.Path[len(prefix):], "/")
+		if appID == "" {
+			return "", fmt.Errorf("path[%s] without appid", r.URL.Path)
+		}
+		return appID, nil
+	}
+}
+
+// EventHandler http.Handler for open platform event callback
+type EventHandler struct {
+	resolver AppIDResolver
+	option   *Option
+}
+
+// NewEventHandler demo:
+// http.Handle("/webhook/event", webhook.NewEventHandler(webhook.StaticAppID("cli_12345"), nil))
+//
+// A nil resolver serves all apps registered by appconfig.Init, the app is resolved from the payload(see event.EventCallbackAuto).
+func NewEventHandler(resolver AppIDResolver, option *Option) *EventHandler {
+	return &EventHandler{
+		resolver: resolver,
+		option:   fillOption(option),
+	}
+}
+
+func (h *EventHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := replayContext(r, h.option)
+
+	body, err := readBody(w, r, h.option.MaxBodySize)
+	if err != nil {
+		common.Logger(ctx).Errorf("eventReqParamsError: readHttpBodyError err[%v]", err)
+		h.option.ErrorEncoder(w, r, common.ErrEventParams.ErrorWithExtErr(err))
+		return
+	}
+
+	var appID string
+	if h.resolver == nil {
+		appID, err = event.ResolveEventAppID(string(body))
+	} else {
+		appID, err = h.resolver(r)
+	}
+	if err != nil {
+		common.Logger(ctx).Errorf("eventReqParamsError: resolveAppIDError err[%v]", err)
+		h.option.ErrorEncoder(w, r, common.ErrEventParams.ErrorWithExtErr(err))
+		return
+	}
+
+	challenge, err := event.EventCallbackWithHeader(ctx, string(body), appID, LarkHeader(r))
+	common.Logger(ctx).Infof("eventInfo: appid[%s] challenge[%s] err[%v]", appID, challenge, err)
+	if err != nil {
+		h.option.ErrorEncoder(w, r, err)
+	} else if challenge != "" {
+		h.option.ResponseEncoder(w, r, map[string]string{"challenge": challenge})
+	} else {
+		h.option.ResponseEncoder(w, r, map[string]string{"codemsg": common.Success.String()})
+	}
+}
+
+// CardHandler http.Handler for card action callback
+type CardHandler struct {
+	resolver AppIDResolver
+	option   *Option
+}
+
+// NewCardHandler demo:
+// http.Handle("/webhook/card", webhook.NewCardHandler(webhook.StaticAppID("cli_12345"), nil))
+//
+// A nil resolver serves all apps registered by appconfig.Init, the app is resolved from the payload(see event.CardCallBackAuto).
+func NewCardHandler(resolver AppIDResolver, option *Option) *CardHandler {
+	return &CardHandler{
+		resolver: resolver,
+		option:   fillOption(option),
+	}
+}
+
+func (h *CardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := replayContext(r, h.option)
+
+	body, err := readBody(w, r, h.option.MaxBodySize)
+	if err != nil {
+		common.Logger(ctx).Errorf("cardReqParamsError: readHttpBodyError err[%v]", err)
+		h.option.ErrorEncoder(w, r, common.ErrCardParams.ErrorWithExtErr(err))
+		return
+	}
+
+	var appID string
+	if h.resolver == nil {
+		appID, err = event.ResolveCardAppID(ctx, LarkHeader(r), body)
+	} else {
+		appID, err = h.resolver(r)
+	}
+	if err != nil {
+		common.Logger(ctx).Errorf("cardReqParamsError: resolveAppIDError err[%v]", err)
+		h.option.ErrorEncoder(w, r, common.ErrCardParams.ErrorWithExtErr(err))
+		return
+	}
+
+	card, challenge, err := event.CardCallBack(ctx, appID, LarkHeader(r), body)
+	common.Logger(ctx).Infof("cardInfo: appid[%s] challenge[%s] err[%v]", appID, challenge, err)
+	if err != nil {
+		h.option.ErrorEncoder(w, r, err)
+	} else if challenge != "" {
+		h.option.ResponseEncoder(w, r, map[string]string{"challenge": challenge})
+	} else {
+		h.option.ResponseEncoder(w, r, card)
+	}
+}
+
+// LarkHeader get the open platform headers, for verify signature
+func LarkHeader(r *http.Request) map[string]string {
+	return map[string]string{
+		event.HeaderRequestTimestamp: r.Header.Get(event.HeaderRequestTimestamp),
+		event.HeaderRequestNonce:     r.Header.Get(event.HeaderRequestNonce),
+		event.HeaderSignature:        r.Header.Get(event.HeaderSignature),
+	}
+}
+
+func replayContext(r *http.Request, option *Option) context.Context {
+	ctx := r.Context()
+	if !option.TrustReplayHeader {
+		return ctx
+	}
+
+	switch r.Header.Get(event.HeaderReplay) {
+	case event.ReplayValue:
+		return event.WithReplay(ctx, false)
+	case event.ReplayValueRedacted:
+		return event.WithReplay(ctx, true)
+	default:
+		return ctx
+	}
+}
+
+func fillOption(option *Option) *Option {
+	def := DefaultOption()
+	if option == nil {
+		return def
+	}
+
+	op := *option
+	if op.MaxBodySize <= 0 {
+		op.MaxBodySize = def.MaxBodySize
+	}
+	if op.ErrorEncoder == nil {
+		op.ErrorEncoder = def.ErrorEncoder
+	}
+	if op.ResponseEncoder == nil {
+		op.ResponseEncoder = def.ResponseEncoder
+	}
+	return &op
+}
+
+func readBody(w http.ResponseWriter, r *http.Request, maxBodySize int64) ([]byte, error) {
+	if r.Method != http.MethodPost {
+		return nil, fmt.Errorf("method[%s] not allowed", r.Method)
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) == 0 {
+		return nil, fmt.Errorf("body is empty")
+	}
+
+	return body, nil
+}
+
+func writeJson(w http.ResponseWriter, statusCode int, data interface{}) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		statusCode = http.StatusInternalServerError
+		body = []byte(fmt.Sprintf(`{"codemsg":%q}`, common.ErrJsonMarshal.StringWithExtErr(err)))
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	w.Write(body)
+}
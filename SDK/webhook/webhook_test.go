@@ -0,0 +1,147 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package webhook_test
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/larksuite/botframework-go/SDK/appconfig"
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+	"github.com/larksuite/botframework-go/SDK/webhook"
+)
+
+const (
+	testAppID       = "cli_webhook_test"
+	testVerifyToken = "webhook_verify_token"
+)
+
+func init() {
+	appconfig.Init(appconfig.AppConfig{
+		AppID:       testAppID,
+		AppType:     protocol.InternalApp,
+		VerifyToken: testVerifyToken,
+	})
+}
+
+func TestEventHandlerChallenge(t *testing.T) {
+	h := webhook.NewEventHandler(webhook.StaticAppID(testAppID), nil)
+
+	body := fmt.Sprintf(`{"challenge":"abc","token":"%s","type":"url_verification"}`, testVerifyToken)
+	rsp := httptest.NewRecorder()
+	h.ServeHTTP(rsp, httptest.NewRequest(http.MethodPost, "/webhook/event", strings.NewReader(body)))
+
+	if rsp.Code != http.StatusOK {
+		t.Fatalf("challenge: status[%d] body[%s]", rsp.Code, rsp.Body.String())
+	}
+	data := map[string]string{}
+	json.Unmarshal(rsp.Body.Bytes(), &data)
+	if data["challenge"] != "abc" {
+		t.Errorf("challenge: unexpected body[%s]", rsp.Body.String())
+	}
+}
+
+func TestEventHandlerMultiApp(t *testing.T) {
+	called := false
+	event.EventRegister(testAppID, protocol.EventTypeAddBot, func(ctx context.Context, eventBody []byte) error {
+		called = true
+		return nil
+	})
+
+	h := webhook.NewEventHandler(webhook.AppIDFromQuery("appid"), nil)
+
+	body := fmt.Sprintf(`{"token":"%s","type":"event_callback","event":{"type":"add_bot","app_id":"%s"}}`, testVerifyToken, testAppID)
+	rsp := httptest.NewRecorder()
+	h.ServeHTTP(rsp, httptest.NewRequest(http.MethodPost, "/webhook/event?appid="+testAppID, strings.NewReader(body)))
+	if rsp.Code != http.StatusOK || !called {
+		t.Errorf("event: status[%d] called[%t] body[%s]", rsp.Code, called, rsp.Body.String())
+	}
+
+	// unknown app
+	rsp = httptest.NewRecorder()
+	h.ServeHTTP(rsp, httptest.NewRequest(http.MethodPost, "/webhook/event?appid=cli_unknown", strings.NewReader(body)))
+	if rsp.Code != http.StatusInternalServerError {
+		t.Errorf("unknown app: status[%d] body[%s]", rsp.Code, rsp.Body.String())
+	}
+
+	// without app
+	rsp = httptest.NewRecorder()
+	h.ServeHTTP(rsp, httptest.NewRequest(http.MethodPost, "/webhook/event", strings.NewReader(body)))
+	if rsp.Code != http.StatusInternalServerError {
+		t.Errorf("without app: status[%d] body[%s]", rsp.Code, rsp.Body.String())
+	}
+}
+
+func TestCardHandler(t *testing.T) {
+	event.CardRegister(testAppID, "webhook_click", func(ctx context.Context, cardCallback *protocol.CardCallbackForm) (*protocol.CardForm, error) {
+		return &protocol.CardForm{OpenIDs: []string{cardCallback.OpenID}}, nil
+	})
+
+	var encodedErr error
+	h := webhook.NewCardHandler(webhook.AppIDFromPathSuffix("/webhook/card/"), &webhook.Option{
+		ErrorEncoder: func(w http.ResponseWriter, r *http.Request, err error) {
+			encodedErr = err
+			w.WriteHeader(http.StatusBadRequest)
+		},
+	})
+
+	body := `{"open_id":"ou_123","action":{"value":{"method":"webhook_click","sid":"1"}}}`
+	req := httptest.NewRequest(http.MethodPost, "/webhook/card/"+testAppID, strings.NewReader(body))
+	req.Header.Set("X-Lark-Request-Timestamp", "1577808000")
+	req.Header.Set("X-Lark-Request-Nonce", "nonce")
+	req.Header.Set("X-Lark-Signature", fmt.Sprintf("%x", sha1.Sum([]byte("1577808000"+"nonce"+testVerifyToken+body))))
+
+	rsp := httptest.NewRecorder()
+	h.ServeHTTP(rsp, req)
+	if rsp.Code != http.StatusOK {
+		t.Fatalf("card: status[%d] err[%v]", rsp.Code, encodedErr)
+	}
+	card := &protocol.CardForm{}
+	json.Unmarshal(rsp.Body.Bytes(), card)
+	if len(card.OpenIDs) != 1 || card.OpenIDs[0] != "ou_123" {
+		t.Errorf("card: unexpected body[%s]", rsp.Body.String())
+	}
+
+	// invalid signature
+	req = httptest.NewRequest(http.MethodPost, "/webhook/card/"+testAppID, strings.NewReader(body))
+	req.Header.Set("X-Lark-Signature", "invalid")
+	rsp = httptest.NewRecorder()
+	h.ServeHTTP(rsp, req)
+	if rsp.Code != http.StatusBadRequest || encodedErr == nil {
+		t.Errorf("invalid signature: status[%d] err[%v]", rsp.Code, encodedErr)
+	}
+}
+
+func TestEventHandlerAuto(t *testing.T) {
+	called := false
+	event.EventRegister(testAppID, protocol.EventTypeRemoveBot, func(ctx context.Context, eventBody []byte) error {
+		called = true
+		return nil
+	})
+
+	h := webhook.NewEventHandler(nil, nil)
+
+	body := fmt.Sprintf(`{"token":"%s","type":"event_callback","event":{"type":"remove_bot","app_id":"%s"}}`, testVerifyToken, testAppID)
+	rsp := httptest.NewRecorder()
+	h.ServeHTTP(rsp, httptest.NewRequest(http.MethodPost, "/webhook/event", strings.NewReader(body)))
+	if rsp.Code != http.StatusOK || !called {
+		t.Errorf("event: status[%d] called[%t] body[%s]", rsp.Code, called, rsp.Body.String())
+	}
+
+	// unknown app
+	body = `{"token":"unknown","type":"url_verification","challenge":"abc"}`
+	rsp = httptest.NewRecorder()
+	h.ServeHTTP(rsp, httptest.NewRequest(http.MethodPost, "/webhook/event", strings.NewReader(body)))
+	if rsp.Code != http.StatusInternalServerError {
+		t.Errorf("unknown app: status[%d] body[%s]", rsp.Code, rsp.Body.String())
+	}
+}
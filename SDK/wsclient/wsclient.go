@@ -0,0 +1,388 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+// Package wsclient receive the event and card callbacks by an outbound websocket connection,
+// for the bots running without public ingress. The frames are dispatched by event.Router,
+// so the handlers registered by event.EventRegister/BotRecvMsgRegister/CardRegister work as they are.
+package wsclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+)
+
+const (
+	FrameTypeEvent = "event" // server => client, the event callback
+	FrameTypeCard  = "card"  // server => client, the card action callback
+	FrameTypeAck   = "ack"   // client => server, the response of the event/card frame
+
+	DefaultHeartbeatInterval   = 30 * time.Second
+	DefaultReconnectMinBackoff = time.Second
+	DefaultReconnectMaxBackoff = 60 * time.Second
+
+	AckCodeSuccess = 0
+	AckCodeFailed  = 1 // the message of the ack is the error
+
+	writeTimeout = 10 * time.Second
+)
+
+// Frame the text message of the connection.
+// The event/card frame carries the same header and body as the http callback,
+// the ack frame carries the ID of the frame it responds to.
+type Frame struct {
+	Type    string            `json:"type"`
+	ID      string            `json:"id"`
+	AppID   string            `json:"app_id,omitempty"` // empty means the app is resolved from the body, see event.EventCallbackAuto
+	Header  map[string]string `json:"header,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	Code    int               `json:"code"`              // ack: AckCodeSuccess or AckCodeFailed
+	Message string            `json:"message,omitempty"` // ack: the error message
+	Data    json.RawMessage   `json:"data,omitempty"`    // ack: the challenge response of the event, or the card to update
+}
+
+type Option struct {
+	URL                 string      // the websocket endpoint, eg: wss://example.com/bot/ws
+	Header              http.Header // the header of the handshake, eg: the auth token of the endpoint
+	AppID               string      // the app of the frames without app_id, empty means the app is resolved from the body
+	Router              *event.Router
+	HeartbeatInterval   time.Duration // the interval of the ping, the connection is closed if no pong in 2 intervals
+	ReconnectMinBackoff time.Duration
+	ReconnectMaxBackoff time.Duration
+	Dialer              *websocket.Dialer
+}
+
+type Client struct {
+	option *Option
+
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+	connMu  sync.Mutex
+
+	drainer   *common.Drainer // the frames being handled
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewClient demo:
+// client, err := wsclient.NewClient(&wsclient.Option{URL: "wss://example.com/bot/ws", AppID: appID})
+// go client.Start(ctx)
+// defer client.Shutdown(shutdownCtx)
+//
+// Router nil means event.DefaultRouter(), the durations <= 0 mean the default values.
+func NewClient(option *Option) (*Client, error) {
+	if option == nil || option.URL == "" {
+		return nil, common.ErrEventWsParams.ErrorWithExtStr("url is empty")
+	}
+
+	opt := *option
+	if opt.Router == nil {
+		opt.Router = event.DefaultRouter()
+	}
+	if opt.HeartbeatInterval <= 0 {
+		opt.HeartbeatInterval = DefaultHeartbeatInterval
+	}
+	if opt.ReconnectMinBackoff <= 0 {
+		opt.ReconnectMinBackoff = DefaultReconnectMinBackoff
+	}
+	if opt.ReconnectMaxBackoff < opt.ReconnectMinBackoff {
+		opt.ReconnectMaxBackoff = DefaultReconnectMaxBackoff
+		if opt.ReconnectMaxBackoff < opt.ReconnectMinBackoff {
+			opt.ReconnectMaxBackoff = opt.ReconnectMinBackoff
+		}
+	}
+	if opt.Dialer == nil {
+		opt.Dialer = websocket.DefaultDialer
+	}
+
+	return &Client{
+		option:  &opt,
+		drainer: &common.Drainer{},
+		closed:  make(chan struct{}),
+	}, nil
+}
+
+// Start connect to the endpoint and dispatch the frames, reconnect with exponential backoff when the connection is broken.
+// Block until Close or Shutdown is called. When ctx is done, the client is shutdown gracefully, as Shutdown without timeout.
+//
+// The frames are handled with the values of ctx, but not with its cancellation:
+// the frames being handled are waited by Shutdown, and the connection is only closed by Close/Shutdown.
+func (c *Client) Start(ctx context.Context) error {
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.Shutdown(common.DetachContext(ctx))
+		case <-c.closed:
+		}
+	}()
+
+	handleCtx := common.DetachContext(ctx)
+	// dialing and the backoff are canceled by Close
+	runCtx, cancel := context.WithCancel(handleCtx)
+	defer cancel()
+	go func() {
+		select {
+		case <-c.closed:
+			cancel()
+		case <-runCtx.Done():
+		}
+	}()
+
+	backoff := c.option.ReconnectMinBackoff
+	for {
+		connected, err := c.run(runCtx, handleCtx)
+		if runCtx.Err() != nil || c.drainer.IsDraining() {
+			return nil
+		}
+		if connected {
+			backoff = c.option.ReconnectMinBackoff
+		}
+		common.Logger(ctx).Warnf("wsClient: disconnected, url[%s]error[%v]reconnect after[%v]", c.option.URL, err, backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-runCtx.Done():
+			return nil
+		}
+
+		backoff *= 2
+		if backoff > c.option.ReconnectMaxBackoff {
+			backoff = c.option.ReconnectMaxBackoff
+		}
+	}
+}
+
+// Shutdown stop reading the frames, and wait for the frames being handled to write their acks, then close the connection.
+// The frames received after Shutdown are not handled, the server should send them again.
+// Return error if ctx is done before all frames are handled.
+func (c *Client) Shutdown(ctx context.Context) error {
+	c.connMu.Lock()
+	conn := c.conn
+	c.connMu.Unlock()
+	c.drainer.Stop()
+	if conn != nil {
+		// unblock ReadMessage, the connection is kept for the acks
+		conn.SetReadDeadline(time.Now())
+	}
+
+	err := c.drainer.Drain(ctx)
+	c.Close()
+	return err
+}
+
+// Close the connection and stop Start
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	if c.conn != nil {
+		return c.conn.Close()
+	}
+	return nil
+}
+
+// run one connection until runCtx is done, return whether the connection is established
+func (c *Client) run(runCtx, ctx context.Context) (bool, error) {
+	conn, _, err := c.option.Dialer.DialContext(runCtx, c.option.URL, c.option.Header)
+	if err != nil {
+		return false, common.ErrEventWsConnect.ErrorWithExtErr(err)
+	}
+	c.setConn(conn)
+	defer func() {
+		// the connection is closed by Shutdown after the acks are written
+		if !c.drainer.IsDraining() {
+			c.setConn(nil)
+			conn.Close()
+		}
+	}()
+
+	common.Logger(ctx).Infof("wsClient: connected, url[%s]", c.option.URL)
+
+	// the read deadline is extended by any message, include the pong
+	timeout := 2 * c.option.HeartbeatInterval
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(timeout))
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+	go c.heartbeat(runCtx, conn, done)
+
+	for {
+		msgType, data, err := conn.ReadMessage()
+		if c.drainer.IsDraining() {
+			return true, nil
+		}
+		if err != nil {
+			return true, common.ErrEventWsConnect.ErrorWithExtErr(err)
+		}
+		conn.SetReadDeadline(time.Now().Add(timeout))
+
+		if msgType != websocket.TextMessage {
+			continue
+		}
+
+		frame := &Frame{}
+		err = json.Unmarshal(data, frame)
+		if err != nil {
+			common.Logger(ctx).Errorf("wsClient: unmarshal frame error[%v]", err)
+			continue
+		}
+
+		if !c.drainer.Acquire() {
+			return true, nil
+		}
+		go func() {
+			defer c.drainer.Release()
+			c.dispatch(ctx, conn, frame)
+		}()
+	}
+}
+
+func (c *Client) heartbeat(ctx context.Context, conn *websocket.Conn, done chan struct{}) {
+	ticker := time.NewTicker(c.option.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.writeMu.Lock()
+			err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout))
+			c.writeMu.Unlock()
+			if err != nil {
+				common.Logger(ctx).Warnf("wsClient: ping error[%v]", err)
+				conn.Close()
+				return
+			}
+		case <-done:
+			return
+		case <-c.closed:
+			// unblock ReadMessage, eg: the connection is established after Close
+			conn.Close()
+			return
+		}
+	}
+}
+
+// dispatch the frame by the router, and write the ack
+func (c *Client) dispatch(ctx context.Context, conn *websocket.Conn, frame *Frame) {
+	defer func() {
+		if r := recover(); r != nil {
+			common.Logger(ctx).Errorf("wsClient: dispatch panic[%v]frameID[%s]", r, frame.ID)
+		}
+	}()
+
+	var data interface{}
+	var err error
+	switch frame.Type {
+	case FrameTypeEvent:
+		data, err = c.handleEvent(ctx, frame)
+	case FrameTypeCard:
+		data, err = c.handleCard(ctx, frame)
+	default:
+		common.Logger(ctx).Warnf("wsClient: unknown frame type[%s]frameID[%s]", frame.Type, frame.ID)
+		return
+	}
+
+	ack := &Frame{
+		Type: FrameTypeAck,
+		ID:   frame.ID,
+	}
+	if err != nil {
+		common.Logger(ctx).Errorf("wsClient: handle frame error[%v]type[%s]frameID[%s]", err, frame.Type, frame.ID)
+		ack.Code, ack.Message = AckCodeFailed, err.Error()
+	}
+	if data != nil {
+		ack.Data, _ = json.Marshal(data)
+	}
+
+	err = c.write(conn, ack)
+	if err != nil {
+		common.Logger(ctx).Errorf("wsClient: write ack error[%v]frameID[%s]", err, frame.ID)
+	}
+}
+
+// handleEvent return the challenge response of the url_verification, same as the http callback
+func (c *Client) handleEvent(ctx context.Context, frame *Frame) (interface{}, error) {
+	var challenge string
+	var err error
+
+	appID := c.appID(frame)
+	if appID == "" {
+		challenge, err = c.option.Router.EventCallbackAuto(ctx, frame.Body, frame.Header)
+	} else {
+		challenge, err = c.option.Router.EventCallbackWithHeader(ctx, frame.Body, appID, frame.Header)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if challenge != "" {
+		return map[string]string{"challenge": challenge}, nil
+	}
+	return nil, nil
+}
+
+// handleCard return the card to update, or the challenge response
+func (c *Client) handleCard(ctx context.Context, frame *Frame) (interface{}, error) {
+	var card *protocol.CardForm
+	var challenge string
+	var err error
+
+	appID := c.appID(frame)
+	if appID == "" {
+		card, challenge, err = c.option.Router.CardCallBackAuto(ctx, frame.Header, []byte(frame.Body))
+	} else {
+		card, challenge, err = c.option.Router.CardCallBack(ctx, appID, frame.Header, []byte(frame.Body))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if challenge != "" {
+		return map[string]string{"challenge": challenge}, nil
+	}
+	if card != nil {
+		return card, nil
+	}
+	return nil, nil
+}
+
+func (c *Client) appID(frame *Frame) string {
+	if frame.AppID != "" {
+		return frame.AppID
+	}
+	return c.option.AppID
+}
+
+func (c *Client) write(conn *websocket.Conn, frame *Frame) error {
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return common.ErrJsonMarshal.ErrorWithExtErr(err)
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return conn.WriteMessage(websocket.TextMessage, data)
+}
+
+func (c *Client) setConn(conn *websocket.Conn) {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+
+	c.conn = conn
+}
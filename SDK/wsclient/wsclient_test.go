@@ -0,0 +1,306 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package wsclient_test
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/larksuite/botframework-go/SDK/appconfig"
+	"github.com/larksuite/botframework-go/SDK/event"
+	"github.com/larksuite/botframework-go/SDK/protocol"
+	"github.com/larksuite/botframework-go/SDK/wsclient"
+)
+
+const (
+	testAppID       = "cli_wsclient_test"
+	testVerifyToken = "wsclient_verify_token"
+)
+
+func init() {
+	appconfig.Init(appconfig.AppConfig{
+		AppID:       testAppID,
+		AppType:     protocol.InternalApp,
+		VerifyToken: testVerifyToken,
+	})
+}
+
+// testServer the stand-in of the long connection endpoint, the accepted connections are sent to conns
+type testServer struct {
+	*httptest.Server
+	conns chan *websocket.Conn
+	pings int32
+}
+
+func newTestServer(t *testing.T) *testServer {
+	s := &testServer{conns: make(chan *websocket.Conn, 10)}
+	upgrader := websocket.Upgrader{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade error[%v]", err)
+			return
+		}
+		conn.SetPingHandler(func(data string) error {
+			atomic.AddInt32(&s.pings, 1)
+			return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+		})
+		s.conns <- conn
+	}))
+	return s
+}
+
+func (s *testServer) accept(t *testing.T) *websocket.Conn {
+	select {
+	case conn := <-s.conns:
+		return conn
+	case <-time.After(3 * time.Second):
+		t.Fatalf("no connection")
+		return nil
+	}
+}
+
+// call send the frame and wait for the ack
+func call(t *testing.T, conn *websocket.Conn, frame *wsclient.Frame) *wsclient.Frame {
+	err := conn.WriteJSON(frame)
+	if err != nil {
+		t.Fatalf("write frame[%s] error[%v]", frame.ID, err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	ack := &wsclient.Frame{}
+	err = conn.ReadJSON(ack)
+	if err != nil {
+		t.Fatalf("read ack of frame[%s] error[%v]", frame.ID, err)
+	}
+	if ack.Type != wsclient.FrameTypeAck || ack.ID != frame.ID {
+		t.Fatalf("unexpected ack[%+v] of frame[%s]", ack, frame.ID)
+	}
+	return ack
+}
+
+func newEventFrame(id, appID, eventUUID, eventType string) *wsclient.Frame {
+	return &wsclient.Frame{
+		Type:  wsclient.FrameTypeEvent,
+		ID:    id,
+		AppID: appID,
+		Body: fmt.Sprintf(`{"uuid":"%s","token":"%s","ts":"1","type":"event_callback","event":{"type":"%s","app_id":"%s"}}`,
+			eventUUID, testVerifyToken, eventType, testAppID),
+	}
+}
+
+func TestClient(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	router := event.NewRouter()
+	events := make(chan string, 10)
+	router.EventRegister(testAppID, protocol.EventTypeAddBot, func(ctx context.Context, eventBody []byte) error {
+		events <- protocol.EventTypeAddBot
+		return nil
+	})
+	router.CardRegister(testAppID, "ws_click", func(ctx context.Context, cardCallback *protocol.CardCallbackForm) (*protocol.CardForm, error) {
+		return &protocol.CardForm{OpenIDs: []string{cardCallback.OpenID}}, nil
+	})
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer test")
+	client, err := wsclient.NewClient(&wsclient.Option{
+		URL:                 "ws" + strings.TrimPrefix(server.URL, "http"),
+		Header:              header,
+		Router:              router,
+		HeartbeatInterval:   50 * time.Millisecond,
+		ReconnectMinBackoff: 10 * time.Millisecond,
+		ReconnectMaxBackoff: 100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewClient error[%v]", err)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		client.Start(context.Background())
+		close(stopped)
+	}()
+
+	conn := server.accept(t)
+
+	// challenge
+	ack := call(t, conn, &wsclient.Frame{
+		Type:  wsclient.FrameTypeEvent,
+		ID:    "1",
+		AppID: testAppID,
+		Body:  fmt.Sprintf(`{"challenge":"abc","token":"%s","type":"url_verification"}`, testVerifyToken),
+	})
+	data := map[string]string{}
+	json.Unmarshal(ack.Data, &data)
+	if ack.Code != wsclient.AckCodeSuccess || data["challenge"] != "abc" {
+		t.Errorf("challenge: unexpected ack[%+v]", ack)
+	}
+
+	// event, the app is resolved from the body
+	ack = call(t, conn, newEventFrame("2", "", "ws_event_1", protocol.EventTypeAddBot))
+	if ack.Code != wsclient.AckCodeSuccess || len(events) != 1 {
+		t.Errorf("event: ack[%+v] events[%d]", ack, len(events))
+	}
+
+	// unregistered event
+	ack = call(t, conn, newEventFrame("3", testAppID, "ws_event_2", protocol.EventTypeRemoveBot))
+	if ack.Code != wsclient.AckCodeFailed || ack.Message == "" {
+		t.Errorf("unregistered event: ack[%+v]", ack)
+	}
+
+	// card
+	body := `{"open_id":"ou_123","action":{"value":{"method":"ws_click","sid":"1"}}}`
+	ack = call(t, conn, &wsclient.Frame{
+		Type:  wsclient.FrameTypeCard,
+		ID:    "4",
+		AppID: testAppID,
+		Header: map[string]string{
+			event.HeaderRequestTimestamp: "1577808000",
+			event.HeaderRequestNonce:     "nonce",
+			event.HeaderSignature:        fmt.Sprintf("%x", sha1.Sum([]byte("1577808000"+"nonce"+testVerifyToken+body))),
+		},
+		Body: body,
+	})
+	card := &protocol.CardForm{}
+	json.Unmarshal(ack.Data, card)
+	if ack.Code != wsclient.AckCodeSuccess || len(card.OpenIDs) != 1 || card.OpenIDs[0] != "ou_123" {
+		t.Errorf("card: unexpected ack[%+v]", ack)
+	}
+
+	// heartbeat, the pings are handled while reading
+	conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	conn.ReadMessage()
+	if atomic.LoadInt32(&server.pings) == 0 {
+		t.Errorf("heartbeat: no ping")
+	}
+
+	// reconnect after the connection is broken
+	conn.Close()
+	conn = server.accept(t)
+	ack = call(t, conn, newEventFrame("5", testAppID, "ws_event_3", protocol.EventTypeAddBot))
+	if ack.Code != wsclient.AckCodeSuccess || len(events) != 2 {
+		t.Errorf("event after reconnect: ack[%+v] events[%d]", ack, len(events))
+	}
+
+	client.Close()
+	select {
+	case <-stopped:
+	case <-time.After(3 * time.Second):
+		t.Errorf("Start is not stopped by Close")
+	}
+}
+
+func TestClientCancelDrain(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	router := event.NewRouter()
+	handling := make(chan struct{})
+	release := make(chan struct{})
+	var handlerErr atomic.Value
+	router.EventRegister(testAppID, protocol.EventTypeAddBot, func(ctx context.Context, eventBody []byte) error {
+		close(handling)
+		<-release
+		if ctx.Err() != nil {
+			handlerErr.Store(ctx.Err())
+		}
+		return nil
+	})
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer test")
+	client, _ := wsclient.NewClient(&wsclient.Option{
+		URL:    "ws" + strings.TrimPrefix(server.URL, "http"),
+		Header: header,
+		Router: router,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		client.Start(ctx)
+		close(stopped)
+	}()
+	conn := server.accept(t)
+
+	err := conn.WriteJSON(newEventFrame("1", testAppID, "ws_cancel_1", protocol.EventTypeAddBot))
+	if err != nil {
+		t.Fatalf("write frame error[%v]", err)
+	}
+	<-handling
+
+	// the frame in flight is not canceled with ctx, and its ack is written before the connection is closed
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	ack := &wsclient.Frame{}
+	err = conn.ReadJSON(ack)
+	if err != nil || ack.ID != "1" || ack.Code != wsclient.AckCodeSuccess {
+		t.Errorf("ack: ack[%+v]err[%v]", ack, err)
+	}
+	if err := handlerErr.Load(); err != nil {
+		t.Errorf("handler ctx: err[%v]", err)
+	}
+
+	select {
+	case <-stopped:
+	case <-time.After(3 * time.Second):
+		t.Errorf("Start is not stopped after ctx is done")
+	}
+}
+
+func TestClientReconnectBackoff(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client, err := wsclient.NewClient(&wsclient.Option{
+		URL:                 "ws" + strings.TrimPrefix(server.URL, "http"),
+		Router:              event.NewRouter(),
+		ReconnectMinBackoff: 20 * time.Millisecond,
+		ReconnectMaxBackoff: 40 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewClient error[%v]", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	client.Start(ctx)
+
+	// 20ms + 40ms + 40ms ... in 300ms
+	n := atomic.LoadInt32(&attempts)
+	if n < 3 || n > 10 {
+		t.Errorf("attempts[%d]", n)
+	}
+}
+
+func TestNewClientParams(t *testing.T) {
+	_, err := wsclient.NewClient(&wsclient.Option{})
+	if err == nil {
+		t.Errorf("empty url: err is nil")
+	}
+}
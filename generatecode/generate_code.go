@@ -8,9 +8,9 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"html/template"
 	"io/ioutil"
 	"os"
+	"text/template"
 
 	"github.com/larksuite/botframework-go/SDK/common"
 )
@@ -26,8 +26,15 @@ func EventCallback(c *gin.Context) {
 		return
 	}
 
+	// for verify signature
+	header := map[string]string{
+		"X-Lark-Request-Timestamp": c.Request.Header.Get("X-Lark-Request-Timestamp"),
+		"X-Lark-Request-Nonce":     c.Request.Header.Get("X-Lark-Request-Nonce"),
+		"X-Lark-Signature":         c.Request.Header.Get("X-Lark-Signature"),
+	}
+
 	appID := "{{.AppID}}"
-	challenge, err := event.EventCallback(c, string(body), appID)
+	challenge, err := event.EventCallbackWithHeader(c, string(body), appID, header)
 	common.Logger(c).Infof("eventInfo: challenge[%s] err[%v]", challenge, err)
 	if err != nil {
 		c.JSON(500, gin.H{"codemsg": fmt.Sprintf("%v", err)})
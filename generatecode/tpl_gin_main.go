@@ -8,17 +8,33 @@ var TplGinMain = `package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
 	"github.com/larksuite/botframework-go/SDK/appconfig"
 	"github.com/larksuite/botframework-go/SDK/auth"
+	"github.com/larksuite/botframework-go/SDK/bot"
 	"github.com/larksuite/botframework-go/SDK/common"
 	"github.com/larksuite/botframework-go/SDK/protocol"
+	"github.com/larksuite/botframework-go/SDK/replay"
 	"{{.Path}}/handler_event"
 )
 
+var (
+	replayFile  = flag.String("replay", "", "replay the callbacks recorded by event.FileRecorder with the registered handlers, then exit")
+	replaySpeed = flag.Float64("replay-speed", 0, "1 keeps the intervals of the recording, 2 is twice as fast, 0 sends without waiting")
+	replayTypes = flag.String("replay-types", "", "only replay the event types, separated by comma. eg: message,add_bot")
+	replayAppID = flag.String("replay-appid", "", "only replay the records of the app")
+)
+
 func main() {
+	flag.Parse()
+
 	r := gin.Default()
 
 	common.InitLogger(common.NewCommonLogger(), common.DefaultOption())
@@ -30,12 +46,35 @@ func main() {
 		return
 	}
 
+	if *replayFile != "" {
+		Replay(context.TODO(), *replayFile, &replay.Option{
+			Speed:      *replaySpeed,
+			EventTypes: replay.ParseEventTypes(*replayTypes),
+			AppID:      *replayAppID,
+		})
+		return
+	}
+
 	r.POST("{{.EventWebhook}}", EventCallback) //open platform event callback
 	r.POST("{{.CardWebhook}}", CardCallback)   //card action callback
 
 	// NOTE your business code
 
-	r.Run(":8089")
+	b, err := bot.New(&bot.Option{
+		Workers: []bot.Worker{bot.HTTPServer(&http.Server{Addr: ":8089", Handler: r})},
+	})
+	if err != nil {
+		common.Logger(context.TODO()).Errorf("InitBotError[%v]", err)
+		return
+	}
+
+	// shutdown gracefully on SIGTERM: stop accepting callbacks, and wait for the handlers in flight
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err = b.Run(ctx)
+	if err != nil {
+		common.Logger(ctx).Errorf("RunError[%v]", err)
+	}
 }
 
 func InitInfo() error {
@@ -77,4 +116,18 @@ func InitInfo() error {
 
 	return nil
 }
+
+func Replay(ctx context.Context, path string, option *replay.Option) {
+	records, err := replay.Load(path)
+	if err != nil {
+		common.Logger(ctx).Errorf("loadRecordError[%v]file[%s]", err, path)
+		return
+	}
+
+	result, err := replay.Replay(ctx, records, replay.NewRouterTarget(nil), option)
+	if err != nil {
+		common.Logger(ctx).Errorf("replayError[%v]", err)
+	}
+	common.Logger(ctx).Infof("replay: total[%d]sent[%d]failed[%d]skipped[%d]", result.Total, result.Sent, result.Failed, result.Skipped)
+}
 `
@@ -25,7 +25,7 @@ func RegistHandler(appID string) {
 	event.EventRegister(appID, protocol.EventType{{.EventName}}, Event{{.EventName}}){{end}}
 
 	// regist bot recv message handler {{range .BotCmdList}}
-	event.BotRecvMsgRegister(appID, "{{.Cmd}}", BotRecvMsg{{.FuncName}}){{end}}
+	event.BotRecvMsgRegisterWithInfo(appID, "{{.Cmd}}", BotRecvMsg{{.FuncName}}, &event.CommandInfo{Description: {{printf "%q" .Description}}}){{end}}
 
 	// regist card action handler {{range .CardList}}
 	event.CardRegister(appID, "{{.MethodName}}", Action{{.FuncName}}){{end}}
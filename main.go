@@ -27,6 +27,12 @@ func init() {
 }
 
 func main() {
+	// botframework-go replay -file callback.jsonl -event-url http://127.0.0.1:8089/webhook/event
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		Replay(os.Args[2:])
+		return
+	}
+
 	flag.Parse()
 
 	if help {
@@ -0,0 +1,84 @@
+// Copyright (c) 2019 Bytedance Inc.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	"github.com/larksuite/botframework-go/SDK/common"
+	"github.com/larksuite/botframework-go/SDK/replay"
+)
+
+// Replay send the callbacks recorded by event.FileRecorder to a running server,
+// or to the registered handlers of the generated project in-process, by its -replay flag
+func Replay(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	file := fs.String("file", "", "recording file path(json lines)")
+	eventURL := fs.String("event-url", "", "event callback url, eg: http://127.0.0.1:8089/webhook/event")
+	cardURL := fs.String("card-url", "", "card callback url, eg: http://127.0.0.1:8089/webhook/card")
+	speed := fs.Float64("speed", 0, "1 keeps the intervals of the recording, 2 is twice as fast, 0 sends without waiting")
+	eventTypes := fs.String("types", "", "only replay the event types, separated by comma. eg: message,add_bot")
+	appID := fs.String("appid", "", "only replay the records of the app")
+	project := fs.String("project", "", "the project dir generated by botframework-go, replay in-process with its registered handlers instead of the urls")
+	fs.Parse(args)
+
+	if *file == "" || (*eventURL == "" && *cardURL == "" && *project == "") {
+		fs.Usage()
+		return
+	}
+
+	common.InitLogger(common.NewCommonLogger(), common.DefaultOption())
+	defer common.FlushLogger()
+	ctx := context.Background()
+
+	if *project != "" {
+		err := replayProject(*project, *file, *speed, *eventTypes, *appID)
+		if err != nil {
+			common.Logger(ctx).Errorf("replayProjectError[%v]project[%s]", err, *project)
+		}
+		return
+	}
+
+	records, err := replay.Load(*file)
+	if err != nil {
+		common.Logger(ctx).Errorf("loadRecordError[%v]file[%s]", err, *file)
+		return
+	}
+
+	option := &replay.Option{
+		Speed:      *speed,
+		EventTypes: replay.ParseEventTypes(*eventTypes),
+		AppID:      *appID,
+	}
+
+	result, err := replay.Replay(ctx, records, replay.NewHTTPTarget(*eventURL, *cardURL), option)
+	if err != nil {
+		common.Logger(ctx).Errorf("replayError[%v]", err)
+	}
+	common.Logger(ctx).Infof("replay: total[%d]sent[%d]failed[%d]skipped[%d]", result.Total, result.Sent, result.Failed, result.Skipped)
+}
+
+// replayProject run the generated main with the -replay flags, see generatecode.TplGinMain
+func replayProject(project, file string, speed float64, eventTypes, appID string) error {
+	path, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command("go", "run", ".",
+		"-replay", path,
+		"-replay-speed", strconv.FormatFloat(speed, 'f', -1, 64),
+		"-replay-types", eventTypes,
+		"-replay-appid", appID)
+	cmd.Dir = project
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}